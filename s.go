@@ -11,49 +11,137 @@
 package semver
 
 import (
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"math"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 // Version represents a parsed version. See http://semver.org/ for
 // detailed description of the various components.
 type Version struct {
+	Epoch      int      // The epoch; see ParseEpoch.
 	Major      int      // The major version number.
 	Minor      int      // The minor version number.
 	Patch      int      // The patch version number.
+	Extra      []int    // Further version numbers; see ParseExtended.
 	Prerelease []string // The pre-release version (dot-separated elements)
 	Build      []string // The build version (dot-separated elements)
 }
 
-var charClasses = strings.NewReplacer("d", `[\pNd]`, "c", `[\-\pNd\pL]`)
+// digitClass and identChars are the characters allowed in version numbers
+// and, within a bracketed class, in identifiers.
+const (
+	digitClass = `[0-9]`
+	identChars = `\-\pNd\pL`
+)
+
+var charClasses = strings.NewReplacer("d", digitClass, "c", "["+identChars+"]")
 
 const pattern = `^(d{1,9})\.(d{1,9})\.(d{1,9})(-c+(\.c+)*)?(\+c+(\.c+)*)?$`
 
 var versionPat = regexp.MustCompile(charClasses.Replace(pattern))
 
+var rawPat = regexp.MustCompile(charClasses.Replace(strings.Replace(pattern, "{1,9}", "+", -1)))
+
+var truncatedPat = regexp.MustCompile(charClasses.Replace(`^d{1,9}(\.(d{1,9}\.?)?)?$`))
+
+var prefixPat = regexp.MustCompile(charClasses.Replace(strings.TrimSuffix(pattern, "$")))
+
+var specIdentPat = regexp.MustCompile(`^[0-9A-Za-z-]+$`)
+
+var extendedPat = regexp.MustCompile(charClasses.Replace(
+	`^(d{1,9})\.(d{1,9})\.(d{1,9})((\.d{1,9})*)(-c+(\.c+)*)?(\+c+(\.c+)*)?$`))
+
+var epochPat = regexp.MustCompile(charClasses.Replace(`^(d{1,9}):`))
+
+var (
+	partialPat = regexp.MustCompile(charClasses.Replace(`^v?(d{1,9})(\.(d{1,9}))?$`))
+	coercePat  = regexp.MustCompile(charClasses.Replace(`(d+)(\.(d+))?(\.(d+))?`))
+)
+
+// ErrInvalid is returned, possibly wrapped, for an invalid version.
+var ErrInvalid = errors.New("invalid version")
+
+// ErrEmpty is returned by Parse for an empty or whitespace-only version.
+// errors.Is(ErrEmpty, ErrInvalid) reports true.
+var ErrEmpty error = invalidError("empty version")
+
+// ErrTruncated is returned, wrapped, by Parse for a version that ends
+// before its patch number, such as 1.2. or 1. errors.Is(ErrTruncated,
+// ErrInvalid) reports true.
+var ErrTruncated error = invalidError("truncated version")
+
+// invalidError is an error that is also ErrInvalid.
+type invalidError string
+
+func (e invalidError) Error() string { return string(e) }
+
+func (e invalidError) Is(target error) bool { return target == ErrInvalid }
+
 // Parse parses the version, which is of one of the following forms:
 //     1.2.3
 //     1.2.3-prerelease
 //     1.2.3+build
 //     1.2.3-prerelease+build
 func Parse(s string) (*Version, error) {
+	v := new(Version)
+	if err := ParseInto(s, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// ParseInto is like Parse but stores the version in v, reusing the
+// capacity of its Prerelease and Build slices. Slices previously taken from
+// v may therefore be overwritten. v is unchanged on error.
+func ParseInto(s string, v *Version) error {
 	m := versionPat.FindStringSubmatch(s)
 	if m == nil {
-		return nil, fmt.Errorf("invalid version %q", s)
+		if strings.TrimFunc(s, unicode.IsSpace) == "" {
+			return ErrEmpty
+		}
+		if m := truncatedPat.FindStringSubmatch(s); m != nil {
+			if m[2] == "" {
+				return fmt.Errorf("%w: missing minor %q", ErrTruncated, s)
+			}
+			return fmt.Errorf("%w: missing patch %q", ErrTruncated, s)
+		}
+		return fmt.Errorf("%w %q", ErrInvalid, s)
 	}
-	v := new(Version)
-	v.Major = atoi(m[1])
-	v.Minor = atoi(m[2])
-	v.Patch = atoi(m[3])
+	fill(v, m)
+	return nil
+}
+
+// fill stores the version matched by pattern in v, where m holds the
+// submatches.
+func fill(v *Version, m []string) {
+	pre, build := v.Prerelease[:0], v.Build[:0]
+	*v = Version{Major: atoi(m[1]), Minor: atoi(m[2]), Patch: atoi(m[3])}
 	if m[4] != "" {
-		v.Prerelease = strings.Split(m[4][1:], ".")
+		v.Prerelease = appendIds(pre, m[4][1:])
 	}
 	if m[6] != "" {
-		v.Build = strings.Split(m[6][1:], ".")
+		v.Build = appendIds(build, m[6][1:])
+	}
+}
+
+// appendIds appends the dot-separated identifiers of s to ids.
+func appendIds(ids []string, s string) []string {
+	for {
+		id, rest, ok := strings.Cut(s, ".")
+		ids = append(ids, id)
+		if !ok {
+			return ids
+		}
+		s = rest
 	}
-	return v, nil
 }
 
 // atoi is the same as strconv.Atoi but assumes that
@@ -66,15 +154,27 @@ func atoi(s string) int {
 	return n
 }
 
+// String returns the version string of v. Prerelease and build
+// identifiers, including unicode letters such as β, are reproduced
+// verbatim, so Parse(s).String() == s for any valid s without leading zeros
+// in the major, minor or patch numbers.
 func (v Version) String() string {
 	var pre, build string
-	if v.Prerelease != nil {
+	if len(v.Prerelease) != 0 {
 		pre = "-" + strings.Join(v.Prerelease, ".")
 	}
-	if v.Build != nil {
+	if len(v.Build) != 0 {
 		build = "+" + strings.Join(v.Build, ".")
 	}
-	return fmt.Sprintf("%d.%d.%d%s%s", v.Major, v.Minor, v.Patch, pre, build)
+	var extra string
+	for _, n := range v.Extra {
+		extra += "." + strconv.Itoa(n)
+	}
+	s := fmt.Sprintf("%d.%d.%d%s%s%s", v.Major, v.Minor, v.Patch, extra, pre, build)
+	if v.Epoch != 0 {
+		s = strconv.Itoa(v.Epoch) + ":" + s
+	}
+	return s
 }
 
 func allDigits(s string) bool {
@@ -161,15 +261,21 @@ func numCmp(a, b string) int {
 // version sequence than w.
 func (v *Version) Less(w *Version) bool {
 	switch {
+	case v.Epoch != w.Epoch:
+		return v.Epoch < w.Epoch
 	case v.Major != w.Major:
 		return v.Major < w.Major
 	case v.Minor != w.Minor:
 		return v.Minor < w.Minor
 	case v.Patch != w.Patch:
 		return v.Patch < w.Patch
+	case cmpInts(v.Extra, w.Extra) != 0:
+		return cmpInts(v.Extra, w.Extra) < 0
+	case len(v.Prerelease)+len(w.Prerelease)+len(v.Build)+len(w.Build) == 0:
+		return false
 	case !eqIds(v.Prerelease, w.Prerelease):
-		if v.Prerelease == nil || w.Prerelease == nil {
-			return v.Prerelease != nil
+		if len(v.Prerelease) == 0 || len(w.Prerelease) == 0 {
+			return len(v.Prerelease) != 0
 		}
 		return lessIds(v.Prerelease, w.Prerelease)
 	case !eqIds(v.Build, w.Build):
@@ -180,9 +286,11 @@ func (v *Version) Less(w *Version) bool {
 
 // Equal returns whether v is semantically equal with w
 func (v *Version) Equal(w *Version) bool {
-	if v.Major == w.Major &&
+	if v.Epoch == w.Epoch &&
+		v.Major == w.Major &&
 		v.Minor == w.Minor &&
-		v.Patch == w.Patch {
+		v.Patch == w.Patch &&
+		cmpInts(v.Extra, w.Extra) == 0 {
 		if len(v.Prerelease) == len(w.Prerelease) {
 			for i := range v.Prerelease {
 				if v.Prerelease[i] != w.Prerelease[i] {
@@ -195,3 +303,1649 @@ func (v *Version) Equal(w *Version) bool {
 	}
 	return false
 }
+
+// sortVersions sorts vs in ascending order using Less.
+func sortVersions(vs []*Version) {
+	sort.SliceStable(vs, func(i, j int) bool { return vs[i].Less(vs[j]) })
+}
+
+// GroupByMajor returns vs bucketed by Major. Each bucket is sorted in
+// ascending order.
+func GroupByMajor(vs []*Version) map[int][]*Version {
+	m := make(map[int][]*Version)
+	for _, v := range vs {
+		m[v.Major] = append(m[v.Major], v)
+	}
+	for _, g := range m {
+		sortVersions(g)
+	}
+	return m
+}
+
+// GroupByMinor returns vs bucketed by [Major, Minor]. Each bucket is sorted
+// in ascending order.
+func GroupByMinor(vs []*Version) map[[2]int][]*Version {
+	m := make(map[[2]int][]*Version)
+	for _, v := range vs {
+		k := [2]int{v.Major, v.Minor}
+		m[k] = append(m[k], v)
+	}
+	for _, g := range m {
+		sortVersions(g)
+	}
+	return m
+}
+
+// Format describes the form of the string accepted by ParseAny.
+type Format int
+
+const (
+	FormatStrict    Format = iota // 1.2.3, 1.2.3-prerelease+build
+	FormatVPrefixed               // v1.2.3
+	FormatPartial                 // 1.2 or 1, optionally v-prefixed
+	FormatCoerced                 // The first version found in other text
+)
+
+// ParseAny parses s leniently and reports the format it was found in.
+// Missing minor and patch numbers default to 0. A coerced version is taken
+// from the first numbers in s, has no prerelease or build, and is rejected
+// if any of its numbers has more than 9 digits.
+func ParseAny(s string) (*Version, Format, error) {
+	if v, err := Parse(s); err == nil {
+		return v, FormatStrict, nil
+	}
+	if strings.HasPrefix(s, "v") {
+		if v, err := Parse(s[1:]); err == nil {
+			return v, FormatVPrefixed, nil
+		}
+	}
+	if m := partialPat.FindStringSubmatch(s); m != nil {
+		return partial(m[1], m[3], ""), FormatPartial, nil
+	}
+	if m := coercePat.FindStringSubmatch(s); m != nil && len(m[1]) <= 9 && len(m[3]) <= 9 && len(m[5]) <= 9 {
+		return partial(m[1], m[3], m[5]), FormatCoerced, nil
+	}
+	return nil, FormatStrict, fmt.Errorf("%w %q", ErrInvalid, s)
+}
+
+// partial returns the version with the given core numbers, treating
+// empty strings as 0.
+func partial(major, minor, patch string) *Version {
+	v := new(Version)
+	v.Major = atoi(major)
+	if minor != "" {
+		v.Minor = atoi(minor)
+	}
+	if patch != "" {
+		v.Patch = atoi(patch)
+	}
+	return v
+}
+
+// ResolveLatest returns the highest version in vs that has no prerelease,
+// or nil if there is none. It is the resolution of a "latest" version
+// request.
+func ResolveLatest(vs []*Version) *Version {
+	var max *Version
+	for _, v := range vs {
+		if len(v.Prerelease) == 0 && (max == nil || max.Less(v)) {
+			max = v
+		}
+	}
+	return max
+}
+
+// sameCore returns whether v and w have the same epoch, major, minor, patch
+// and extra numbers.
+func sameCore(v, w *Version) bool {
+	return v.Epoch == w.Epoch && v.Major == w.Major && v.Minor == w.Minor && v.Patch == w.Patch &&
+		cmpInts(v.Extra, w.Extra) == 0
+}
+
+// IsFinalOf returns whether release is the final release of the prerelease
+// pre; i.e. both have the same major, minor and patch numbers, pre has a
+// prerelease and release does not.
+func (pre *Version) IsFinalOf(release *Version) bool {
+	return sameCore(pre, release) &&
+		len(pre.Prerelease) != 0 && len(release.Prerelease) == 0
+}
+
+// intCmp returns 1, -1 or 0 if a is greater-than, less-than or equal to b,
+// respectively.
+func intCmp(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// cmpIds compares the slices of identifiers a and b as specified in
+// semver.org. It returns 1, -1 or 0 if a is greater-than, less-than or
+// equal to b, respectively.
+func cmpIds(a, b []string) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if c := cmp(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+	return intCmp(len(a), len(b))
+}
+
+// Compare returns 1, -1 or 0 if v has greater, lower or equal precedence
+// than w, respectively. Build metadata is ignored, as specified in
+// semver.org, which gives the example ordering:
+//
+//	1.0.0-alpha < 1.0.0-alpha.1 < 1.0.0-alpha.beta < 1.0.0-beta <
+//	1.0.0-beta.2 < 1.0.0-beta.11 < 1.0.0-rc.1 < 1.0.0
+func (v *Version) Compare(w *Version) int {
+	return v.CompareWith(w, CompareOpts{})
+}
+
+// CompareOpts modifies the precedence rules used by CompareWith. The zero
+// value selects the rules specified in semver.org.
+type CompareOpts struct {
+	// PrereleaseHigherPrecedence gives a version with a prerelease higher
+	// precedence than the same version without one.
+	PrereleaseHigherPrecedence bool
+
+	// PrereleaseOrder orders prerelease identifiers by weight, lowest
+	// first; i.e. {"dev": 0, "alpha": 1, "beta": 2, "rc": 3}. Identifiers
+	// that are not both listed are compared as specified in semver.org.
+	PrereleaseOrder map[string]int
+}
+
+// CompareWith is like Compare but uses the precedence rules of opts.
+func (v *Version) CompareWith(w *Version, opts CompareOpts) int {
+	final := 1
+	if opts.PrereleaseHigherPrecedence {
+		final = -1
+	}
+	switch {
+	case v.Epoch != w.Epoch:
+		return intCmp(v.Epoch, w.Epoch)
+	case v.Major != w.Major:
+		return intCmp(v.Major, w.Major)
+	case v.Minor != w.Minor:
+		return intCmp(v.Minor, w.Minor)
+	case v.Patch != w.Patch:
+		return intCmp(v.Patch, w.Patch)
+	case cmpInts(v.Extra, w.Extra) != 0:
+		return cmpInts(v.Extra, w.Extra)
+	case len(v.Prerelease) == 0 && len(w.Prerelease) == 0:
+		return 0
+	case len(v.Prerelease) == 0:
+		return final
+	case len(w.Prerelease) == 0:
+		return -final
+	}
+	if opts.PrereleaseOrder == nil {
+		return cmpIds(v.Prerelease, w.Prerelease)
+	}
+	for i := 0; i < len(v.Prerelease) && i < len(w.Prerelease); i++ {
+		a, b := v.Prerelease[i], w.Prerelease[i]
+		c := cmp(a, b)
+		if wa, ok := opts.PrereleaseOrder[a]; ok {
+			if wb, ok := opts.PrereleaseOrder[b]; ok {
+				c = intCmp(wa, wb)
+			}
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return intCmp(len(v.Prerelease), len(w.Prerelease))
+}
+
+// Unique returns the versions of vs with distinct precedence, sorted in
+// ascending order. Of versions that differ only in build metadata, the
+// first in vs is kept. vs is not modified.
+func Unique(vs []*Version) []*Version {
+	s := make([]*Version, len(vs))
+	copy(s, vs)
+	sort.SliceStable(s, func(i, j int) bool { return s[i].Compare(s[j]) < 0 })
+	u := s[:0]
+	for _, v := range s {
+		if len(u) == 0 || u[len(u)-1].Compare(v) != 0 {
+			u = append(u, v)
+		}
+	}
+	return u
+}
+
+// Range returns the versions of vs between from and to, sorted in ascending
+// order. incFrom and incTo report whether versions with the same precedence
+// as from and to, respectively, are included.
+func Range(vs []*Version, from, to *Version, incFrom, incTo bool) []*Version {
+	var r []*Version
+	for _, v := range vs {
+		if c := v.Compare(from); c < 0 || c == 0 && !incFrom {
+			continue
+		}
+		if c := v.Compare(to); c > 0 || c == 0 && !incTo {
+			continue
+		}
+		r = append(r, v)
+	}
+	sortVersions(r)
+	return r
+}
+
+// CompareRaw compares the versions a and b, which may have major, minor and
+// patch numbers of any length. It returns 1, -1 or 0 if a has greater, lower
+// or equal precedence than b, respectively. The numbers are compared as
+// strings and never converted to int.
+func CompareRaw(a, b string) (int, error) {
+	ma := rawPat.FindStringSubmatch(a)
+	if ma == nil {
+		return 0, fmt.Errorf("%w %q", ErrInvalid, a)
+	}
+	mb := rawPat.FindStringSubmatch(b)
+	if mb == nil {
+		return 0, fmt.Errorf("%w %q", ErrInvalid, b)
+	}
+	for i := 1; i <= 3; i++ {
+		if c := numCmp(ma[i], mb[i]); c != 0 {
+			return c, nil
+		}
+	}
+	switch pa, pb := ma[4], mb[4]; {
+	case pa == "" && pb == "":
+		return 0, nil
+	case pa == "":
+		return 1, nil
+	case pb == "":
+		return -1, nil
+	default:
+		return cmpIds(strings.Split(pa[1:], "."), strings.Split(pb[1:], ".")), nil
+	}
+}
+
+// Lint parses s leniently and returns the version, or nil if s has no
+// major, minor and patch numbers, along with a description of each way s
+// does not comply with semver.org. Empty identifiers are dropped.
+// Identifiers with characters other than [0-9A-Za-z-], such as the
+// unicode letters that Parse accepts, are kept and reported.
+func Lint(s string) (*Version, []string) {
+	var warn []string
+	rest, build := s, ""
+	hasBuild := false
+	if i := strings.IndexByte(rest, '+'); i >= 0 {
+		rest, build, hasBuild = rest[:i], rest[i+1:], true
+	}
+	core, pre := rest, ""
+	hasPre := false
+	if i := strings.IndexByte(rest, '-'); i >= 0 {
+		core, pre, hasPre = rest[:i], rest[i+1:], true
+	}
+	nums := strings.Split(core, ".")
+	if len(nums) != 3 {
+		return nil, append(warn, fmt.Sprintf("invalid version core %q", core))
+	}
+	var n [3]int
+	for i, name := range []string{"major", "minor", "patch"} {
+		d := nums[i]
+		if d == "" || !allDigits(d) || len(d) > 9 {
+			return nil, append(warn, fmt.Sprintf("invalid %s number %q", name, d))
+		}
+		if len(d) > 1 && d[0] == '0' {
+			warn = append(warn, fmt.Sprintf("%s number %q has a leading zero", name, d))
+		}
+		n[i] = atoi(d)
+	}
+	v := &Version{Major: n[0], Minor: n[1], Patch: n[2]}
+	lintIds := func(kind, ids string) []string {
+		var r []string
+		for _, id := range strings.Split(ids, ".") {
+			switch {
+			case id == "":
+				warn = append(warn, fmt.Sprintf("empty %s identifier", kind))
+				continue
+			case !specIdentPat.MatchString(id):
+				warn = append(warn, fmt.Sprintf("%s identifier %q has invalid characters", kind, id))
+			case kind == "prerelease" && len(id) > 1 && id[0] == '0' && allDigits(id):
+				warn = append(warn, fmt.Sprintf("%s identifier %q has a leading zero", kind, id))
+			}
+			r = append(r, id)
+		}
+		return r
+	}
+	if hasPre {
+		v.Prerelease = lintIds("prerelease", pre)
+	}
+	if hasBuild {
+		v.Build = lintIds("build", build)
+	}
+	return v, warn
+}
+
+// EqualWithBuild returns whether v is semantically equal with w and both
+// have exactly the same build identifiers.
+func (v *Version) EqualWithBuild(w *Version) bool {
+	return v.Equal(w) && sameIds(v.Build, w.Build)
+}
+
+// sameIds returns whether a and b hold exactly the same identifiers.
+func sameIds(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i, s := range a {
+		if s != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Components returns the major, minor and patch numbers of v. Parse never
+// produces negative numbers, but a Version constructed directly may hold
+// them; Components returns them unchanged.
+func (v *Version) Components() (major, minor, patch int) {
+	return v.Major, v.Minor, v.Patch
+}
+
+// CoreKey returns the major, minor and patch numbers of v as an array,
+// which may be used as a map key.
+func (v *Version) CoreKey() [3]int {
+	return [3]int{v.Major, v.Minor, v.Patch}
+}
+
+// CoreKey holds the major, minor and patch numbers of a version. Unlike
+// Version it is comparable, so may be used as a map key.
+type CoreKey struct {
+	Major, Minor, Patch int
+}
+
+// CoreKeyOf returns the CoreKey of v.
+func CoreKeyOf(v *Version) CoreKey {
+	return CoreKey{v.Major, v.Minor, v.Patch}
+}
+
+// ParseEpoch parses a version with an optional epoch prefix, as used by
+// Debian and other package managers; i.e. 1:1.2.3. The epoch takes
+// precedence over all other components. A version without an epoch has
+// epoch 0. Epochs are not part of semver.org.
+func ParseEpoch(s string) (*Version, error) {
+	m := epochPat.FindStringSubmatch(s)
+	if m == nil {
+		return Parse(s)
+	}
+	v, err := Parse(s[len(m[0]):])
+	if err != nil {
+		return nil, fmt.Errorf("%w %q", ErrInvalid, s)
+	}
+	v.Epoch = atoi(m[1])
+	return v, nil
+}
+
+// clone returns a copy of v that shares no slices with v.
+func (v *Version) clone() *Version {
+	w := *v
+	if v.Extra != nil {
+		w.Extra = append([]int(nil), v.Extra...)
+	}
+	if v.Prerelease != nil {
+		w.Prerelease = append([]string(nil), v.Prerelease...)
+	}
+	if v.Build != nil {
+		w.Build = append([]string(nil), v.Build...)
+	}
+	return &w
+}
+
+// BumpCounter returns a copy of v with the prerelease counter label
+// incremented, and without build metadata. If the prerelease of v ends with
+// label followed by a numeric identifier, it is incremented; otherwise
+// label and 1 are appended; i.e. 1.2.3 becomes 1.2.3-build.1, which becomes
+// 1.2.3-build.2. A counter that cannot be incremented without overflowing
+// int is treated as no counter, so label and 1 are appended after it.
+func (v *Version) BumpCounter(label string) *Version {
+	w := v.clone()
+	w.Build = nil
+	if n := len(w.Prerelease); n >= 2 && w.Prerelease[n-2] == label && allDigits(w.Prerelease[n-1]) {
+		if c, err := strconv.Atoi(w.Prerelease[n-1]); err == nil && c < math.MaxInt {
+			w.Prerelease[n-1] = strconv.Itoa(c + 1)
+			return w
+		}
+	}
+	w.Prerelease = append(w.Prerelease, label, "1")
+	return w
+}
+
+// Describe returns a multi-line, human readable description of v; e.g.
+//
+//	Major: 1
+//	Minor: 2
+//	Patch: 3
+//	Prerelease: rc.1
+//	Build: (none)
+//
+// A nonzero epoch is described on a first line and extra numbers after the
+// patch number.
+func (v *Version) Describe() string {
+	ids := func(s []string) string {
+		if len(s) == 0 {
+			return "(none)"
+		}
+		return strings.Join(s, ".")
+	}
+	var b strings.Builder
+	if v.Epoch != 0 {
+		fmt.Fprintf(&b, "Epoch: %d\n", v.Epoch)
+	}
+	fmt.Fprintf(&b, "Major: %d\nMinor: %d\nPatch: %d\n", v.Major, v.Minor, v.Patch)
+	if len(v.Extra) != 0 {
+		fmt.Fprintf(&b, "Extra: %s\n", strings.Trim(fmt.Sprint(v.Extra), "[]"))
+	}
+	fmt.Fprintf(&b, "Prerelease: %s\nBuild: %s", ids(v.Prerelease), ids(v.Build))
+	return b.String()
+}
+
+// Previous returns the version of vs with the highest precedence lower than
+// v, or nil if there is none. vs need not be sorted and need not contain v.
+func Previous(vs []*Version, v *Version) *Version {
+	var p *Version
+	for _, w := range vs {
+		if w.Compare(v) < 0 && (p == nil || p.Compare(w) < 0) {
+			p = w
+		}
+	}
+	return p
+}
+
+// Next returns the version of vs with the lowest precedence higher than v,
+// or nil if there is none. vs need not be sorted and need not contain v.
+func Next(vs []*Version, v *Version) *Version {
+	var n *Version
+	for _, w := range vs {
+		if w.Compare(v) > 0 && (n == nil || w.Compare(n) < 0) {
+			n = w
+		}
+	}
+	return n
+}
+
+// IndexSorted returns a function that reports the index in vs of a version
+// with the same precedence as its argument, or where one would be inserted,
+// and whether there is one. vs must be sorted in ascending order and must
+// not be modified while the function is in use. Each call is a binary
+// search.
+func IndexSorted(vs []*Version) func(*Version) (int, bool) {
+	return func(v *Version) (int, bool) {
+		i := sort.Search(len(vs), func(i int) bool { return vs[i].Compare(v) >= 0 })
+		return i, i < len(vs) && vs[i].Compare(v) == 0
+	}
+}
+
+// DeepEqual returns whether all fields of v and w are identical, including
+// build metadata, and distinguishing nil from empty slices. This is not
+// semver.org equality; see Equal.
+func (v *Version) DeepEqual(w *Version) bool {
+	return sameCore(v, w) && (v.Extra == nil) == (w.Extra == nil) &&
+		idsIdentical(v.Prerelease, w.Prerelease) && idsIdentical(v.Build, w.Build)
+}
+
+// idsIdentical returns whether a and b are both nil or hold identical
+// identifiers.
+func idsIdentical(a, b []string) bool {
+	return (a == nil) == (b == nil) && sameIds(a, b)
+}
+
+// ParseTrim is like Parse but first removes leading and trailing unicode
+// white space and byte order marks from s. White space within the version
+// is still rejected.
+func ParseTrim(s string) (*Version, error) {
+	return Parse(strings.TrimFunc(s, func(r rune) bool {
+		return unicode.IsSpace(r) || r == '\uFEFF'
+	}))
+}
+
+// CompareWithBuild is like Compare, but orders versions with equal
+// precedence by their build identifiers. Identifiers that are both numeric
+// are compared numerically, so +2 is less than +10; others are compared
+// bytewise.
+func (v *Version) CompareWithBuild(w *Version) int {
+	if c := v.Compare(w); c != 0 {
+		return c
+	}
+	for i := 0; i < len(v.Build) && i < len(w.Build); i++ {
+		a, b := v.Build[i], w.Build[i]
+		c := strings.Compare(a, b)
+		if allDigits(a) && allDigits(b) {
+			c = numCmp(a, b)
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return intCmp(len(v.Build), len(w.Build))
+}
+
+// CompareBuildLexical is like Compare, but orders versions with equal
+// precedence by their build identifiers, each compared bytewise. Unlike
+// CompareWithBuild, numeric build identifiers are not compared
+// numerically, so 01 and 1 differ and 10 is less than 9. This gives a
+// total order for deduplicating artifacts.
+func (v *Version) CompareBuildLexical(w *Version) int {
+	if c := v.Compare(w); c != 0 {
+		return c
+	}
+	for i := 0; i < len(v.Build) && i < len(w.Build); i++ {
+		if c := strings.Compare(v.Build[i], w.Build[i]); c != 0 {
+			return c
+		}
+	}
+	return intCmp(len(v.Build), len(w.Build))
+}
+
+// IsSorted returns whether vs is sorted in ascending order of precedence.
+// Build metadata is ignored.
+func IsSorted(vs []*Version) bool {
+	for i := 1; i < len(vs); i++ {
+		if vs[i].Compare(vs[i-1]) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Kind identifies a major, minor or patch number.
+type Kind int
+
+const (
+	KindMajor Kind = iota
+	KindMinor
+	KindPatch
+)
+
+// CanInc returns whether the number identified by k can be incremented
+// without overflowing int.
+func (v *Version) CanInc(k Kind) bool {
+	switch k {
+	case KindMajor:
+		return v.Major < math.MaxInt
+	case KindMinor:
+		return v.Minor < math.MaxInt
+	case KindPatch:
+		return v.Patch < math.MaxInt
+	}
+	return false
+}
+
+// ParseNoBuild is like Parse but rejects versions with build metadata.
+func ParseNoBuild(s string) (*Version, error) {
+	v, err := Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	if v.Build != nil {
+		return nil, fmt.Errorf("%w %q: build metadata not allowed", ErrInvalid, s)
+	}
+	return v, nil
+}
+
+// MergeBuild returns a copy of v with the build identifiers of other that
+// v does not already have appended. Neither v nor other is modified.
+func (v *Version) MergeBuild(other *Version) *Version {
+	w := v.clone()
+	seen := make(map[string]bool, len(w.Build))
+	for _, id := range w.Build {
+		seen[id] = true
+	}
+	for _, id := range other.Build {
+		if !seen[id] {
+			seen[id] = true
+			w.Build = append(w.Build, id)
+		}
+	}
+	return w
+}
+
+// ParseGoModule parses a Go module version, such as v1.2.3 or
+// v2.0.0+incompatible, as printed by go list -m. The v prefix is optional.
+// The incompatible suffix is kept in Build, which Compare ignores, and is
+// rejected for major versions below 2.
+func ParseGoModule(s string) (*Version, error) {
+	v, err := Parse(strings.TrimPrefix(s, "v"))
+	if err != nil {
+		return nil, fmt.Errorf("%w %q", ErrInvalid, s)
+	}
+	if len(v.Build) == 1 && v.Build[0] == "incompatible" && v.Major < 2 {
+		return nil, fmt.Errorf("%w %q: +incompatible requires major version 2 or higher", ErrInvalid, s)
+	}
+	return v, nil
+}
+
+// ParseValid parses each of ss and returns the valid versions in the order
+// of ss. Invalid versions are discarded.
+func ParseValid(ss []string) []*Version {
+	var vs []*Version
+	for _, s := range ss {
+		if v, err := Parse(s); err == nil {
+			vs = append(vs, v)
+		}
+	}
+	return vs
+}
+
+// Train returns the patch release train of v; i.e. 1.2.x for 1.2.3-rc.1.
+func (v *Version) Train() string {
+	return fmt.Sprintf("%d.%d.x", v.Major, v.Minor)
+}
+
+// MajorTrain returns the major release train of v; i.e. 1.x for 1.2.3.
+func (v *Version) MajorTrain() string {
+	return fmt.Sprintf("%d.x", v.Major)
+}
+
+// VersionValues implements sort.Interface for a slice of Version values,
+// ordering them with Less.
+type VersionValues []Version
+
+func (s VersionValues) Len() int           { return len(s) }
+func (s VersionValues) Less(i, j int) bool { return s[i].Less(&s[j]) }
+func (s VersionValues) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// CompareStrings parses the versions a and b and returns the result of
+// comparing their precedence with op, which is one of >, >=, <, <=, == or
+// !=.
+func CompareStrings(a, op, b string) (bool, error) {
+	v, err := Parse(a)
+	if err != nil {
+		return false, err
+	}
+	w, err := Parse(b)
+	if err != nil {
+		return false, err
+	}
+	c := v.Compare(w)
+	switch op {
+	case ">":
+		return c > 0, nil
+	case ">=":
+		return c >= 0, nil
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case "==":
+		return c == 0, nil
+	case "!=":
+		return c != 0, nil
+	}
+	return false, fmt.Errorf("invalid operator %q", op)
+}
+
+// IsProductionReady returns whether v is a production release: it has no
+// prerelease, no build metadata and a major version of at least 1.
+func (v *Version) IsProductionReady() bool {
+	return len(v.Prerelease) == 0 && len(v.Build) == 0 && v.Major >= 1
+}
+
+// cmpInts returns 1, -1 or 0 if the numbers a are greater-than, less-than or
+// equal to b, respectively, compared in order. A prefix of b is less than b.
+func cmpInts(a, b []int) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if c := intCmp(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+	return intCmp(len(a), len(b))
+}
+
+// ParseExtended is like Parse but also accepts further numbers after the
+// patch number, such as 1.2.3.4, which are stored in Extra. They are
+// compared in order after the patch number; 1.2.3 is less than 1.2.3.0.
+// Extended versions are not part of semver.org.
+func ParseExtended(s string) (*Version, error) {
+	m := extendedPat.FindStringSubmatch(s)
+	if m == nil {
+		return nil, fmt.Errorf("%w %q", ErrInvalid, s)
+	}
+	v := new(Version)
+	v.Major = atoi(m[1])
+	v.Minor = atoi(m[2])
+	v.Patch = atoi(m[3])
+	if m[4] != "" {
+		for _, n := range strings.Split(m[4][1:], ".") {
+			v.Extra = append(v.Extra, atoi(n))
+		}
+	}
+	if m[6] != "" {
+		v.Prerelease = strings.Split(m[6][1:], ".")
+	}
+	if m[8] != "" {
+		v.Build = strings.Split(m[8][1:], ".")
+	}
+	return v, nil
+}
+
+// RoundTrip parses s and reports whether parsing the String of the result
+// gives an identical version. It returns the error of either parse.
+func RoundTrip(s string) (bool, error) {
+	v, err := Parse(s)
+	if err != nil {
+		return false, err
+	}
+	w, err := Parse(v.String())
+	if err != nil {
+		return false, err
+	}
+	return v.DeepEqual(w), nil
+}
+
+// WithinPatch returns whether v and w have the same major and minor
+// numbers and patch numbers that differ by at most tolerance. Prereleases
+// and build metadata are ignored.
+func (v *Version) WithinPatch(w *Version, tolerance int) bool {
+	d := v.Patch - w.Patch
+	if d < 0 {
+		d = -d
+	}
+	return v.Major == w.Major && v.Minor == w.Minor && d <= tolerance
+}
+
+// Join returns the versions of vs, sorted in ascending order, joined by sep.
+// vs is not modified.
+func Join(vs []*Version, sep string) string {
+	return join(vs, sep, false)
+}
+
+// JoinDesc is like Join but sorts in descending order.
+func JoinDesc(vs []*Version, sep string) string {
+	return join(vs, sep, true)
+}
+
+func join(vs []*Version, sep string, desc bool) string {
+	s := make([]*Version, len(vs))
+	copy(s, vs)
+	sortVersions(s)
+	ss := make([]string, len(s))
+	for i, v := range s {
+		if desc {
+			i = len(s) - 1 - i
+		}
+		ss[i] = v.String()
+	}
+	return strings.Join(ss, sep)
+}
+
+// IsEvenMinor returns whether the minor number of v is even, which denotes
+// a stable release in some projects.
+func (v *Version) IsEvenMinor() bool {
+	return v.Minor%2 == 0
+}
+
+// PrereleaseStage interprets the prerelease of v as a named stage and an
+// optional number; i.e. rc.2 gives "rc", 2, true and beta gives "beta", 0,
+// false. For other prereleases, such as alpha.1.2, name is the first
+// identifier and hasNum is false. name is empty if v has no prerelease.
+func (v *Version) PrereleaseStage() (name string, num int, hasNum bool) {
+	switch len(v.Prerelease) {
+	case 0:
+		return "", 0, false
+	case 2:
+		if allDigits(v.Prerelease[1]) {
+			if n, err := strconv.Atoi(v.Prerelease[1]); err == nil {
+				return v.Prerelease[0], n, true
+			}
+		}
+	}
+	return v.Prerelease[0], 0, false
+}
+
+// IsNextAfter returns whether candidate has higher precedence than current.
+func IsNextAfter(current, candidate *Version) bool {
+	return candidate.Compare(current) > 0
+}
+
+// ValidateMonotonic returns an error describing the first version of vs
+// that does not have higher precedence than the one before it, or nil if
+// vs is strictly increasing.
+func ValidateMonotonic(vs []*Version) error {
+	for i := 1; i < len(vs); i++ {
+		if !IsNextAfter(vs[i-1], vs[i]) {
+			return fmt.Errorf("version %v at index %d does not follow %v", vs[i], i, vs[i-1])
+		}
+	}
+	return nil
+}
+
+// Comparer compares versions. Compare returns 1, -1 or 0 if a is
+// greater-than, less-than or equal to b, respectively.
+type Comparer interface {
+	Compare(a, b *Version) int
+}
+
+// DefaultComparer compares versions with Compare, as specified in
+// semver.org.
+var DefaultComparer Comparer = specComparer{}
+
+type specComparer struct{}
+
+func (specComparer) Compare(a, b *Version) int { return a.Compare(b) }
+
+// SortWith sorts vs in ascending order using c, or DefaultComparer if c is
+// nil. The sort is stable.
+func SortWith(vs []*Version, c Comparer) {
+	if c == nil {
+		c = DefaultComparer
+	}
+	sort.SliceStable(vs, func(i, j int) bool { return c.Compare(vs[i], vs[j]) < 0 })
+}
+
+// MaxWith returns the greatest version of vs using c, or DefaultComparer if
+// c is nil. It returns nil if vs is empty.
+func MaxWith(vs []*Version, c Comparer) *Version {
+	if c == nil {
+		c = DefaultComparer
+	}
+	var max *Version
+	for _, v := range vs {
+		if max == nil || c.Compare(max, v) < 0 {
+			max = v
+		}
+	}
+	return max
+}
+
+// LatestPerMajor returns the highest version of vs without a prerelease
+// for each major number.
+func LatestPerMajor(vs []*Version) map[int]*Version {
+	m := make(map[int]*Version)
+	for _, v := range vs {
+		if max := m[v.Major]; len(v.Prerelease) == 0 && (max == nil || max.Less(v)) {
+			m[v.Major] = v
+		}
+	}
+	return m
+}
+
+// LatestPerMinor returns the highest version of vs without a prerelease
+// for each [Major, Minor].
+func LatestPerMinor(vs []*Version) map[[2]int]*Version {
+	m := make(map[[2]int]*Version)
+	for _, v := range vs {
+		k := [2]int{v.Major, v.Minor}
+		if max := m[k]; len(v.Prerelease) == 0 && (max == nil || max.Less(v)) {
+			m[k] = v
+		}
+	}
+	return m
+}
+
+// QueryKind identifies the kind of a Go module version query.
+type QueryKind int
+
+const (
+	QueryLatest  QueryKind = iota // latest
+	QueryUpgrade                  // upgrade
+	QueryPatch                    // patch
+	QueryVersion                  // A version, such as v1.2.3
+)
+
+// Query is a Go module version query, as accepted by go get.
+type Query struct {
+	Kind    QueryKind
+	Version *Version // The version of a QueryVersion query.
+}
+
+// ParseGoQuery parses a Go module version query: latest, upgrade, patch or
+// a version such as v1.2.3. A leading @ is optional.
+func ParseGoQuery(s string) (Query, error) {
+	switch q := strings.TrimPrefix(s, "@"); q {
+	case "latest":
+		return Query{Kind: QueryLatest}, nil
+	case "upgrade":
+		return Query{Kind: QueryUpgrade}, nil
+	case "patch":
+		return Query{Kind: QueryPatch}, nil
+	default:
+		if !strings.HasPrefix(q, "v") {
+			return Query{}, fmt.Errorf("invalid version query %q", s)
+		}
+		v, err := ParseGoModule(q)
+		if err != nil {
+			return Query{}, fmt.Errorf("invalid version query %q", s)
+		}
+		return Query{Kind: QueryVersion, Version: v}, nil
+	}
+}
+
+// Resolve returns the version of vs selected by q given the current
+// version, which may be nil, or nil if there is none. As with go get:
+//   - latest selects the highest version without a prerelease, or the
+//     highest prerelease if there is none;
+//   - upgrade is like latest but selects current if it is higher;
+//   - patch is like upgrade but only considers versions with the major and
+//     minor numbers of current, and is like latest if current is nil;
+//   - a version selects the version of vs with the same precedence.
+func (q Query) Resolve(current *Version, vs []*Version) *Version {
+	switch q.Kind {
+	case QueryLatest:
+		return latest(vs)
+	case QueryUpgrade:
+		return noDowngrade(current, latest(vs))
+	case QueryPatch:
+		if current == nil {
+			return latest(vs)
+		}
+		var s []*Version
+		for _, v := range vs {
+			if v.Major == current.Major && v.Minor == current.Minor {
+				s = append(s, v)
+			}
+		}
+		return noDowngrade(current, latest(s))
+	case QueryVersion:
+		for _, v := range vs {
+			if v.Compare(q.Version) == 0 {
+				return v
+			}
+		}
+	}
+	return nil
+}
+
+// latest returns the highest version of vs without a prerelease, or the
+// highest prerelease if there is none.
+func latest(vs []*Version) *Version {
+	if v := ResolveLatest(vs); v != nil {
+		return v
+	}
+	var max *Version
+	for _, v := range vs {
+		if max == nil || max.Less(v) {
+			max = v
+		}
+	}
+	return max
+}
+
+// noDowngrade returns v, or current if current is not nil and has higher
+// precedence.
+func noDowngrade(current, v *Version) *Version {
+	if current != nil && (v == nil || v.Compare(current) < 0) {
+		return current
+	}
+	return v
+}
+
+// CompatibleUpperBound returns the lowest version that is not compatible
+// with v under caret rules, which is an exclusive upper bound: 2.0.0 for
+// 1.2.3, 0.3.0 for 0.2.3 and 0.0.4 for 0.0.3.
+func (v *Version) CompatibleUpperBound() *Version {
+	switch {
+	case v.Major != 0:
+		return &Version{Epoch: v.Epoch, Major: v.Major + 1}
+	case v.Minor != 0:
+		return &Version{Epoch: v.Epoch, Minor: v.Minor + 1}
+	}
+	return &Version{Epoch: v.Epoch, Patch: v.Patch + 1}
+}
+
+// EqualCoreBuild returns whether v and w have the same major, minor and
+// patch numbers and exactly the same build identifiers. Prereleases are
+// ignored.
+func (v *Version) EqualCoreBuild(w *Version) bool {
+	return sameCore(v, w) && sameIds(v.Build, w.Build)
+}
+
+// Index returns the index of the first version of vs with the same
+// precedence as target, or -1 if there is none. Build metadata is ignored.
+func Index(vs []*Version, target *Version) int {
+	for i, v := range vs {
+		if v.Compare(target) == 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+// Contains returns whether vs has a version with the same precedence as
+// target. Build metadata is ignored.
+func Contains(vs []*Version, target *Version) bool {
+	return Index(vs, target) >= 0
+}
+
+// SortKey returns a key for v such that bytes.Compare of the keys of two
+// versions equals their Compare. Build metadata is not part of the key.
+func (v *Version) SortKey() []byte {
+	var b []byte
+	num := func(n int) {
+		b = binary.BigEndian.AppendUint64(b, uint64(n)^1<<63)
+	}
+	num(v.Epoch)
+	num(v.Major)
+	num(v.Minor)
+	num(v.Patch)
+	for _, n := range v.Extra {
+		b = append(b, 1)
+		num(n)
+	}
+	b = append(b, 0)
+	if len(v.Prerelease) == 0 {
+		// A version without a prerelease follows all its prereleases.
+		return append(b, 1)
+	}
+	b = append(b, 0)
+	for _, id := range v.Prerelease {
+		if allDigits(id) {
+			id = strings.TrimLeft(id, "0")
+			b = append(b, 1)
+			b = binary.BigEndian.AppendUint32(b, uint32(len(id)))
+			b = append(b, id...)
+		} else {
+			b = append(b, 2)
+			b = append(b, id...)
+			b = append(b, 0)
+		}
+	}
+	return append(b, 0)
+}
+
+// ParsePrefix parses the longest version at the start of s and returns it
+// with the rest of s; i.e. 1.2.3 and " and more" for "1.2.3 and more".
+// The version must not be followed by +, an identifier character, or .
+// and an identifier character, so a number with more than 9 digits, an
+// invalid identifier or a version with more than three numbers, such as
+// 1.2.3.4, is rejected rather than cut in two.
+func ParsePrefix(s string) (*Version, string, error) {
+	m := prefixPat.FindString(s)
+	if m == "" {
+		return nil, s, fmt.Errorf("%w %q", ErrInvalid, s)
+	}
+	rest := s[len(m):]
+	if strings.HasPrefix(rest, "+") || isIdentStart(rest) ||
+		strings.HasPrefix(rest, ".") && isIdentStart(rest[1:]) {
+		return nil, s, fmt.Errorf("%w %q", ErrInvalid, s)
+	}
+	v, err := Parse(m)
+	if err != nil {
+		return nil, s, err
+	}
+	return v, s[len(m):], nil
+}
+
+// isIdentStart returns whether s starts with a character that may appear
+// in an identifier.
+func isIdentStart(s string) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+	return r == '-' || unicode.IsNumber(r) || unicode.IsLetter(r)
+}
+
+// DuplicateCores returns the versions of vs grouped by the epoch, major,
+// minor, patch and further version numbers, in the form 1.2.3 or 1:1.2.3.4,
+// of groups with more than one version.
+func DuplicateCores(vs []*Version) map[string][]*Version {
+	m := make(map[string][]*Version)
+	for _, v := range vs {
+		k := (&Version{Epoch: v.Epoch, Major: v.Major, Minor: v.Minor, Patch: v.Patch, Extra: v.Extra}).String()
+		m[k] = append(m[k], v)
+	}
+	for k, g := range m {
+		if len(g) < 2 {
+			delete(m, k)
+		}
+	}
+	return m
+}
+
+// ParseInfo reports which optional parts were present in a version parsed
+// by ParseDetail.
+type ParseInfo struct {
+	HadPrerelease bool // The version had a prerelease.
+	HadBuild      bool // The version had build metadata.
+	HadVPrefix    bool // The version had a v prefix.
+}
+
+// ParseDetail is like Parse but also accepts a v prefix, and reports which
+// optional parts were present.
+func ParseDetail(s string) (*Version, ParseInfo, error) {
+	var info ParseInfo
+	t := s
+	if strings.HasPrefix(t, "v") {
+		t, info.HadVPrefix = t[1:], true
+	}
+	v, err := Parse(t)
+	if err != nil {
+		return nil, ParseInfo{}, fmt.Errorf("%w %q", ErrInvalid, s)
+	}
+	info.HadPrerelease = v.Prerelease != nil
+	info.HadBuild = v.Build != nil
+	return v, info, nil
+}
+
+// defaultChannels maps the first prerelease identifier of a version to its
+// release channel, as used by Channel.
+var defaultChannels = map[string]string{
+	"alpha": "alpha",
+	"dev":   "alpha",
+	"beta":  "beta",
+	"rc":    "beta",
+}
+
+// DefaultChannels returns a new map of the channels used by Channel, which
+// callers may modify and pass to ChannelWith.
+func DefaultChannels() map[string]string {
+	m := make(map[string]string, len(defaultChannels))
+	for k, c := range defaultChannels {
+		m[k] = c
+	}
+	return m
+}
+
+// Channel returns the release channel of v: "stable" if v has no
+// prerelease, "alpha" if its first prerelease identifier is alpha or dev,
+// "beta" if it is beta or rc, and "unknown" otherwise.
+func (v *Version) Channel() string {
+	return v.ChannelWith(defaultChannels)
+}
+
+// ChannelWith is like Channel but maps the first prerelease identifier
+// with channels instead of DefaultChannels().
+func (v *Version) ChannelWith(channels map[string]string) string {
+	if len(v.Prerelease) == 0 {
+		return "stable"
+	}
+	if c, ok := channels[v.Prerelease[0]]; ok {
+		return c
+	}
+	return "unknown"
+}
+
+// Rank returns the number of versions of vs with lower precedence than v,
+// which is the ascending rank of v among vs. Build metadata is ignored.
+func Rank(vs []*Version, v *Version) int {
+	n := 0
+	for _, w := range vs {
+		if w.Compare(v) < 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// ParseWithCharset is like Parse but also allows the characters of extra in
+// prerelease and build identifiers. extra may not contain . or +.
+func ParseWithCharset(s string, extra string) (*Version, error) {
+	if strings.ContainsAny(extra, ".+") {
+		return nil, fmt.Errorf("invalid identifier characters %q", extra)
+	}
+	class := identChars
+	for _, r := range extra {
+		class += fmt.Sprintf(`\x{%x}`, r)
+	}
+	pat, err := regexp.Compile(strings.NewReplacer("d", digitClass, "c", "["+class+"]").Replace(pattern))
+	if err != nil {
+		return nil, err
+	}
+	m := pat.FindStringSubmatch(s)
+	if m == nil {
+		return nil, fmt.Errorf("%w %q", ErrInvalid, s)
+	}
+	v := new(Version)
+	fill(v, m)
+	return v, nil
+}
+
+// MinBumpToExceed returns the lowest release with higher precedence than
+// latest, without build metadata: the release of latest if it is a
+// prerelease, otherwise latest with the patch number incremented.
+func (latest *Version) MinBumpToExceed() *Version {
+	w := latest.clone()
+	w.Build = nil
+	if len(w.Prerelease) != 0 {
+		w.Prerelease = nil
+		return w
+	}
+	w.Patch++
+	w.Extra = nil
+	return w
+}
+
+// Identical returns whether v and w have the same numbers and exactly the
+// same prerelease and build identifiers. Unlike DeepEqual, nil and empty
+// slices are the same. The numbers are compared first, so versions that
+// differ in them are rejected without examining any identifiers.
+func (v *Version) Identical(w *Version) bool {
+	if v == w {
+		return true
+	}
+	return sameCore(v, w) && sameIds(v.Prerelease, w.Prerelease) && sameIds(v.Build, w.Build)
+}
+
+// Distance returns a weighted distance between a and b: the difference in
+// major numbers times 10000, plus the difference in minor numbers times
+// 100, plus the difference in patch numbers, each taken as a magnitude.
+// Prereleases and build metadata are ignored. Distances rank differences
+// in major above minor above patch while minor and patch differences are
+// below 100.
+func Distance(a, b *Version) int {
+	abs := func(n int) int {
+		if n < 0 {
+			return -n
+		}
+		return n
+	}
+	return abs(a.Major-b.Major)*10000 + abs(a.Minor-b.Minor)*100 + abs(a.Patch-b.Patch)
+}
+
+// FloorMinor returns a new version with the epoch, major and minor numbers
+// of v; i.e. 1.2.0 for 1.2.3-rc.1.
+func (v *Version) FloorMinor() *Version {
+	return &Version{Epoch: v.Epoch, Major: v.Major, Minor: v.Minor}
+}
+
+// FloorMajor returns a new version with the epoch and major number of v;
+// i.e. 1.0.0 for 1.2.3-rc.1.
+func (v *Version) FloorMajor() *Version {
+	return &Version{Epoch: v.Epoch, Major: v.Major}
+}
+
+// SelectMVS returns the version selected by Go's minimal version selection
+// for a single module required at each of requirements: the highest
+// required version. It returns nil if requirements is empty.
+func SelectMVS(requirements []*Version) *Version {
+	return MaxWith(requirements, nil)
+}
+
+// MarshalLines returns vs as text, one version per line.
+func MarshalLines(vs []*Version) []byte {
+	var b []byte
+	for _, v := range vs {
+		b = append(b, v.String()...)
+		b = append(b, '\n')
+	}
+	return b
+}
+
+// ParseLines parses text with one version per line, as returned by
+// MarshalLines, with ParseTrim. Blank lines are skipped. The error of an
+// invalid version reports its line number.
+func ParseLines(b []byte) ([]*Version, error) {
+	var vs []*Version
+	for i, line := range strings.Split(string(b), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		v, err := ParseTrim(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		vs = append(vs, v)
+	}
+	return vs, nil
+}
+
+// IsDowngradeTo returns whether target has lower precedence than current;
+// e.g. 1.2.3 to 1.2.3-rc.1 is a downgrade.
+func (current *Version) IsDowngradeTo(target *Version) bool {
+	return target.Compare(current) < 0
+}
+
+// Repair parses a malformed version and returns the semver.org compliant
+// version along with a description of each repair made. It trims white
+// space, removes a v prefix, fills in missing minor and patch numbers,
+// removes leading zeros from numbers and drops empty identifiers. It
+// returns an error if s cannot be repaired, including if an identifier has
+// characters other than [0-9A-Za-z-], such as the unicode letters that
+// Parse accepts.
+func Repair(s string) (*Version, []string, error) {
+	var fixes []string
+	t := strings.TrimFunc(s, unicode.IsSpace)
+	if t != s {
+		fixes = append(fixes, "trimmed white space")
+	}
+	if strings.HasPrefix(t, "v") || strings.HasPrefix(t, "V") {
+		fixes = append(fixes, fmt.Sprintf("removed %q prefix", t[:1]))
+		t = t[1:]
+	}
+	rest, build, hasBuild := strings.Cut(t, "+")
+	core, pre, hasPre := strings.Cut(rest, "-")
+	nums := strings.Split(core, ".")
+	if len(nums) > 3 {
+		return nil, nil, fmt.Errorf("%w %q", ErrInvalid, s)
+	}
+	names := []string{"major", "minor", "patch"}
+	for i := len(nums); i < 3; i++ {
+		fixes = append(fixes, fmt.Sprintf("added missing %s number", names[i]))
+		nums = append(nums, "0")
+	}
+	for i, n := range nums {
+		if n == "" || !allDigits(n) || len(strings.TrimLeft(n, "0")) > 9 {
+			return nil, nil, fmt.Errorf("%w %q", ErrInvalid, s)
+		}
+		if len(n) > 1 && n[0] == '0' {
+			fixes = append(fixes, fmt.Sprintf("removed leading zeros from %s number %q", names[i], n))
+		}
+	}
+	v := &Version{Major: atoi(nums[0]), Minor: atoi(nums[1]), Patch: atoi(nums[2])}
+	repairIds := func(kind, ids string) []string {
+		var r []string
+		for _, id := range strings.Split(ids, ".") {
+			switch {
+			case id == "":
+				fixes = append(fixes, fmt.Sprintf("removed empty %s identifier", kind))
+				continue
+			case kind == "prerelease" && len(id) > 1 && id[0] == '0' && allDigits(id):
+				fixes = append(fixes, fmt.Sprintf("removed leading zeros from %s identifier %q", kind, id))
+				if id = strings.TrimLeft(id, "0"); id == "" {
+					id = "0"
+				}
+			}
+			r = append(r, id)
+		}
+		return r
+	}
+	if hasPre {
+		v.Prerelease = repairIds("prerelease", pre)
+	}
+	if hasBuild {
+		v.Build = repairIds("build", build)
+	}
+	for _, ids := range [][]string{v.Prerelease, v.Build} {
+		for _, id := range ids {
+			if !specIdentPat.MatchString(id) {
+				return nil, nil, fmt.Errorf("%w %q: identifier %q is not compliant with semver.org", ErrInvalid, s, id)
+			}
+		}
+	}
+	if _, err := Parse(v.String()); err != nil {
+		return nil, nil, fmt.Errorf("%w %q", ErrInvalid, s)
+	}
+	return v, fixes, nil
+}
+
+// Added returns the versions of newVs with a precedence that no version of
+// oldVs has, as returned by Unique. Build metadata is ignored.
+func Added(oldVs, newVs []*Version) []*Version {
+	var r []*Version
+	for _, v := range Unique(newVs) {
+		if !Contains(oldVs, v) {
+			r = append(r, v)
+		}
+	}
+	return r
+}
+
+// Removed returns the versions of oldVs with a precedence that no version
+// of newVs has, as returned by Unique. Build metadata is ignored.
+func Removed(oldVs, newVs []*Version) []*Version {
+	return Added(newVs, oldVs)
+}
+
+// ParseCandidates returns each distinct version that s parses as with
+// Parse, ParseTrim, ParseAny, ParseExtended and ParseEpoch, in that order.
+// It returns nil if s parses with none of them.
+func ParseCandidates(s string) []*Version {
+	var vs []*Version
+	add := func(v *Version, err error) {
+		if err != nil {
+			return
+		}
+		for _, w := range vs {
+			if w.DeepEqual(v) {
+				return
+			}
+		}
+		vs = append(vs, v)
+	}
+	add(Parse(s))
+	add(ParseTrim(s))
+	v, _, err := ParseAny(s)
+	add(v, err)
+	add(ParseExtended(s))
+	add(ParseEpoch(s))
+	return vs
+}
+
+// Slug returns String of v made safe for file names and URLs: + becomes _
+// and any other character except ASCII letters, digits, . and - becomes ~
+// followed by its code point as six hex digits; i.e. 1.2.3-β+build becomes
+// 1.2.3-~0003b2_build. Distinct versions have distinct slugs.
+func (v *Version) Slug() string {
+	var b strings.Builder
+	for _, r := range v.String() {
+		switch {
+		case r == '+':
+			b.WriteByte('_')
+		case r == '.' || r == '-' || '0' <= r && r <= '9' || 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z':
+			b.WriteRune(r)
+		default:
+			fmt.Fprintf(&b, "~%06x", r)
+		}
+	}
+	return b.String()
+}
+
+// CompareIgnoringPrerelease is like Compare but ignores prereleases, so
+// that 1.2.3-rc.1 and 1.2.3 are equal and both less than 1.2.4.
+func (v *Version) CompareIgnoringPrerelease(w *Version) int {
+	switch {
+	case v.Epoch != w.Epoch:
+		return intCmp(v.Epoch, w.Epoch)
+	case v.Major != w.Major:
+		return intCmp(v.Major, w.Major)
+	case v.Minor != w.Minor:
+		return intCmp(v.Minor, w.Minor)
+	case v.Patch != w.Patch:
+		return intCmp(v.Patch, w.Patch)
+	}
+	return cmpInts(v.Extra, w.Extra)
+}
+
+// ParseRequireLines parses the module requirements of a go.mod require
+// block, with lines such as example.com/mod v1.2.3, and returns the version
+// of each module path. The require keyword, parentheses, blank lines and
+// comments are accepted. Versions are parsed with ParseGoModule. The error
+// for an invalid line reports its line number.
+func ParseRequireLines(b []byte) (map[string]*Version, error) {
+	m := make(map[string]*Version)
+	for i, line := range strings.Split(string(b), "\n") {
+		if j := strings.Index(line, "//"); j >= 0 {
+			line = line[:j]
+		}
+		f := strings.Fields(line)
+		if len(f) > 0 && f[0] == "require" {
+			f = f[1:]
+		}
+		switch {
+		case len(f) == 0, len(f) == 1 && (f[0] == "(" || f[0] == ")"):
+			continue
+		case len(f) != 2:
+			return nil, fmt.Errorf("line %d: invalid requirement %q", i+1, strings.TrimSpace(line))
+		}
+		v, err := ParseGoModule(f[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		m[f[0]] = v
+	}
+	return m, nil
+}
+
+// SamePrerelease returns whether v and w have prereleases of equal
+// precedence, regardless of their other components. Two versions without
+// prereleases have the same prerelease.
+func (v *Version) SamePrerelease(w *Version) bool {
+	return eqIds(v.Prerelease, w.Prerelease)
+}
+
+// SortStable sorts vs in ascending order using CompareBuildLexical, so
+// that versions of equal precedence are ordered by build metadata rather
+// than by their order in vs.
+func SortStable(vs []*Version) {
+	sort.SliceStable(vs, func(i, j int) bool { return vs[i].CompareBuildLexical(vs[j]) < 0 })
+}
+
+// CommonPrefix returns the major, minor and patch numbers shared by all of
+// vs, in the form 1, 1.2 or 1.2.3, or "" if vs is empty or the major
+// numbers differ.
+func CommonPrefix(vs []*Version) string {
+	if len(vs) == 0 {
+		return ""
+	}
+	n := 3
+	for _, v := range vs[1:] {
+		switch {
+		case v.Major != vs[0].Major:
+			n = 0
+		case v.Minor != vs[0].Minor && n > 1:
+			n = 1
+		case v.Patch != vs[0].Patch && n > 2:
+			n = 2
+		}
+	}
+	nums := []string{strconv.Itoa(vs[0].Major), strconv.Itoa(vs[0].Minor), strconv.Itoa(vs[0].Patch)}
+	return strings.Join(nums[:n], ".")
+}
+
+// CompareTo is the same as Compare, for use with generic containers that
+// require a CompareTo method.
+func (v *Version) CompareTo(w *Version) int {
+	return v.Compare(w)
+}
+
+// maxPatchRange is the most versions PatchRange returns.
+const maxPatchRange = 10000
+
+// PatchRange returns the versions from from to to, inclusive, with each
+// patch number in between; i.e. 1.2.0, 1.2.1, ..., 1.2.5 for 1.2.0 and
+// 1.2.5. from and to must have the same epoch, major and minor numbers and
+// span at most 10000 versions. Prereleases and build metadata are ignored.
+func PatchRange(from, to *Version) ([]*Version, error) {
+	switch {
+	case from.Epoch != to.Epoch || from.Major != to.Major || from.Minor != to.Minor:
+		return nil, fmt.Errorf("versions %v and %v differ in major or minor", from, to)
+	case from.Patch > to.Patch:
+		return nil, fmt.Errorf("version %v is after %v", from, to)
+	case to.Patch-from.Patch >= maxPatchRange:
+		return nil, fmt.Errorf("range %v to %v has more than %d versions", from, to, maxPatchRange)
+	}
+	vs := make([]*Version, to.Patch-from.Patch+1)
+	for i := range vs {
+		vs[i] = &Version{Epoch: from.Epoch, Major: from.Major, Minor: from.Minor, Patch: from.Patch + i}
+	}
+	return vs, nil
+}
+
+// ParseNormalized is like Parse but reports whether String of the version
+// differs from s, as it does when s has leading zeros in the major, minor
+// or patch numbers; i.e. 1.2.3 for 1.02.3. It returns nil and false if s
+// is invalid.
+func ParseNormalized(s string) (*Version, bool) {
+	v, err := Parse(s)
+	if err != nil {
+		return nil, false
+	}
+	return v, v.String() != s
+}
+
+// DecPrerelease returns a copy of v, without build metadata, with the
+// trailing numeric prerelease identifier decremented; i.e. 1.2.3-rc.2 for
+// 1.2.3-rc.3. It returns an error if the prerelease of v does not end with
+// a number or the number is not above 1.
+func (v *Version) DecPrerelease() (*Version, error) {
+	n := len(v.Prerelease)
+	if n == 0 || !allDigits(v.Prerelease[n-1]) {
+		return nil, fmt.Errorf("version %v has no numeric prerelease", v)
+	}
+	c, err := strconv.Atoi(v.Prerelease[n-1])
+	if err != nil {
+		return nil, fmt.Errorf("version %v: %w", v, err)
+	}
+	if c <= 1 {
+		return nil, fmt.Errorf("version %v: prerelease number %d cannot be decremented", v, c)
+	}
+	w := v.clone()
+	w.Build = nil
+	w.Prerelease[n-1] = strconv.Itoa(c - 1)
+	return w, nil
+}
+
+// Policy is a rule for API compatibility between versions, as used by
+// CompatibleUnder.
+type Policy int
+
+const (
+	// SemverCaret makes w compatible with v if w has at least the
+	// precedence of v and is below v.CompatibleUpperBound(), ignoring
+	// prereleases; i.e. 1.2.3 is compatible with 1.4.0 but not 2.0.0 or
+	// 2.0.0-rc.1, and 0.2.3 with 0.2.9 but not 0.3.0.
+	SemverCaret Policy = iota
+	// SameMajor makes w compatible with v if they have the same major
+	// number.
+	SameMajor
+	// SameMinor makes w compatible with v if they have the same major and
+	// minor numbers.
+	SameMinor
+	// Exact makes w compatible with v if they have equal precedence.
+	Exact
+)
+
+// CompatibleUnder returns whether w is API-compatible with v under policy.
+// Versions with different epochs are never compatible.
+func (v *Version) CompatibleUnder(w *Version, policy Policy) bool {
+	if v.Epoch != w.Epoch {
+		return false
+	}
+	switch policy {
+	case SemverCaret:
+		return w.Compare(v) >= 0 && w.CompareIgnoringPrerelease(v.CompatibleUpperBound()) < 0
+	case SameMajor:
+		return v.Major == w.Major
+	case SameMinor:
+		return v.Major == w.Major && v.Minor == w.Minor
+	case Exact:
+		return v.Compare(w) == 0
+	}
+	return false
+}
+
+// FinalVersion returns the release that v finalizes to: if v is a
+// prerelease, a copy of v without prerelease or build metadata, i.e. 1.2.0
+// for 1.2.0-rc.1, otherwise an unchanged copy of v.
+func (v *Version) FinalVersion() *Version {
+	w := v.clone()
+	if len(w.Prerelease) != 0 {
+		w.Prerelease, w.Build = nil, nil
+	}
+	return w
+}
+
+// PrecedenceTuple returns the components of v that determine precedence,
+// in the order Compare examines them: the epoch, the major, minor and
+// patch numbers, a copy of the further version numbers, and a copy of the
+// prerelease identifiers. Build metadata does not participate. The epoch
+// and further numbers are included because versions parsed with
+// ParseEpoch and ParseExtended are ordered by them, so the major, minor
+// and patch numbers and prerelease alone would not order 1:1.0.0 after
+// 2.0.0 or 1.2.3.4 after 1.2.3.
+func (v *Version) PrecedenceTuple() (epoch, major, minor, patch int, extra []int, prerelease []string) {
+	if len(v.Extra) != 0 {
+		extra = append(extra, v.Extra...)
+	}
+	if len(v.Prerelease) != 0 {
+		prerelease = append(prerelease, v.Prerelease...)
+	}
+	return v.Epoch, v.Major, v.Minor, v.Patch, extra, prerelease
+}
+
+// IsLowestPrerelease returns whether the prerelease of v is exactly 0, the
+// lowest prerelease of its release.
+func (v *Version) IsLowestPrerelease() bool {
+	return len(v.Prerelease) == 1 && v.Prerelease[0] == "0"
+}
+
+// LowestPrerelease returns a copy of v, without build metadata, with the
+// lowest prerelease 0; i.e. 1.2.0-0 for 1.2.0. No prerelease of 1.2.0 has
+// lower precedence.
+func (v *Version) LowestPrerelease() *Version {
+	w := v.clone()
+	w.Prerelease, w.Build = []string{"0"}, nil
+	return w
+}
@@ -0,0 +1,2199 @@
+// Copyright 2016 aletheia7. All rights reserved. Use of this source code is
+// governed by a BSD-2-Clause license that can be found in the LICENSE file.
+
+package semver
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func mustParse(s string) *Version {
+	v, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func parseAll(ss ...string) []*Version {
+	vs := make([]*Version, len(ss))
+	for i, s := range ss {
+		vs[i] = mustParse(s)
+	}
+	return vs
+}
+
+func TestGroupByMajor(t *testing.T) {
+	vs := parseAll("2.1.0", "1.2.0", "2.0.0-rc.1", "1.0.0", "3.0.0", "2.0.0", "1.10.0")
+	got := GroupByMajor(vs)
+	want := map[int]string{
+		1: "[1.0.0 1.2.0 1.10.0]",
+		2: "[2.0.0-rc.1 2.0.0 2.1.0]",
+		3: "[3.0.0]",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d groups, want %d", len(got), len(want))
+	}
+	for k, w := range want {
+		if s := fmt.Sprint(got[k]); s != w {
+			t.Errorf("major %d: got %s, want %s", k, s, w)
+		}
+	}
+}
+
+func TestGroupByMinor(t *testing.T) {
+	vs := parseAll("1.2.3", "1.2.0", "1.3.0", "2.2.0")
+	got := GroupByMinor(vs)
+	want := map[[2]int]string{
+		{1, 2}: "[1.2.0 1.2.3]",
+		{1, 3}: "[1.3.0]",
+		{2, 2}: "[2.2.0]",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d groups, want %d", len(got), len(want))
+	}
+	for k, w := range want {
+		if s := fmt.Sprint(got[k]); s != w {
+			t.Errorf("%v: got %s, want %s", k, s, w)
+		}
+	}
+}
+
+func TestParseAny(t *testing.T) {
+	tests := []struct {
+		in     string
+		want   string
+		format Format
+	}{
+		{"1.2.3-rc.1+b", "1.2.3-rc.1+b", FormatStrict},
+		{"v1.2.3", "1.2.3", FormatVPrefixed},
+		{"v1.2", "1.2.0", FormatPartial},
+		{"1", "1.0.0", FormatPartial},
+		{"release-1.4.2-final", "1.4.2", FormatCoerced},
+		{"build 7.1 of", "7.1.0", FormatCoerced},
+	}
+	for _, tt := range tests {
+		v, f, err := ParseAny(tt.in)
+		if err != nil {
+			t.Errorf("%q: %v", tt.in, err)
+			continue
+		}
+		if v.String() != tt.want || f != tt.format {
+			t.Errorf("%q: got %v, %d, want %s, %d", tt.in, v, f, tt.want, tt.format)
+		}
+	}
+	for _, s := range []string{"", "foo", "release-1234567890", "1.2.1234567890"} {
+		if v, _, err := ParseAny(s); err == nil {
+			t.Errorf("%q: got %v, want error", s, v)
+		}
+	}
+}
+
+func TestResolveLatest(t *testing.T) {
+	tests := []struct {
+		in   []string
+		want string
+	}{
+		{[]string{"1.0.0", "1.2.0", "1.1.0"}, "1.2.0"},
+		{[]string{"1.0.0", "2.0.0-rc.1", "1.5.0"}, "1.5.0"},
+		{[]string{"2.0.0-rc.1"}, "<nil>"},
+		{nil, "<nil>"},
+	}
+	for _, tt := range tests {
+		vs := parseAll(tt.in...)
+		if got := fmt.Sprint(ResolveLatest(vs)); got != tt.want {
+			t.Errorf("%v: got %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestIsFinalOf(t *testing.T) {
+	tests := []struct {
+		pre, release string
+		want         bool
+	}{
+		{"1.2.3-rc.2", "1.2.3", true},
+		{"1.2.3-rc.2", "1.2.3+build", true},
+		{"1.2.3-rc.2", "1.2.4", false},
+		{"1.2.3-rc.2", "1.2.3-rc.3", false},
+		{"1.2.3", "1.2.3", false},
+	}
+	for _, tt := range tests {
+		if got := mustParse(tt.pre).IsFinalOf(mustParse(tt.release)); got != tt.want {
+			t.Errorf("%s.IsFinalOf(%s): got %v, want %v", tt.pre, tt.release, got, tt.want)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"1.2.3", "1.2.3+build", 0},
+		{"1.2.3", "1.2.4", -1},
+		{"2.0.0", "1.9.9", 1},
+		{"1.2.3-rc.1", "1.2.3", -1},
+		{"1.2.3-rc.10", "1.2.3-rc.9", 1},
+		{"1.2.3-01", "1.2.3-1", 0},
+	}
+	for _, tt := range tests {
+		if got := mustParse(tt.a).Compare(mustParse(tt.b)); got != tt.want {
+			t.Errorf("%s.Compare(%s): got %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestUnique(t *testing.T) {
+	vs := parseAll("1.2.3+b", "1.0.0", "1.2.3+a", "1.2.3-rc.1", "1.0.0+x", "1.2.3")
+	got := Unique(vs)
+	if s := fmt.Sprint(got); s != "[1.0.0 1.2.3-rc.1 1.2.3+b]" {
+		t.Errorf("got %s", s)
+	}
+	if s := fmt.Sprint(vs); s != "[1.2.3+b 1.0.0 1.2.3+a 1.2.3-rc.1 1.0.0+x 1.2.3]" {
+		t.Errorf("input modified: %s", s)
+	}
+}
+
+func TestStringEmptySlices(t *testing.T) {
+	tests := []struct {
+		v    Version
+		want string
+	}{
+		{Version{Major: 1, Prerelease: []string{}}, "1.0.0"},
+		{Version{Major: 1, Build: []string{}}, "1.0.0"},
+		{Version{Major: 1, Prerelease: []string{}, Build: []string{}}, "1.0.0"},
+		{Version{Major: 1, Prerelease: []string{"rc"}, Build: []string{}}, "1.0.0-rc"},
+	}
+	for _, tt := range tests {
+		if got := tt.v.String(); got != tt.want {
+			t.Errorf("%#v: got %q, want %q", tt.v, got, tt.want)
+		}
+	}
+	empty := &Version{Major: 1, Prerelease: []string{}}
+	if rc := mustParse("1.0.0-rc"); !rc.Less(empty) || empty.Less(rc) {
+		t.Errorf("empty prerelease does not order like none")
+	}
+	for _, s := range []string{"1.0.0", "1.0.0-rc", "1.0.0+b"} {
+		v := mustParse(s)
+		if v.Prerelease != nil && len(v.Prerelease) == 0 || v.Build != nil && len(v.Build) == 0 {
+			t.Errorf("%s: Parse produced an empty non-nil slice", s)
+		}
+	}
+}
+
+func TestRange(t *testing.T) {
+	vs := parseAll("1.3.0", "1.0.0", "1.1.0", "1.2.0", "1.2.0+b", "0.9.0")
+	from, to := mustParse("1.0.0"), mustParse("1.2.0")
+	tests := []struct {
+		incFrom, incTo bool
+		want           string
+	}{
+		{false, false, "[1.1.0]"},
+		{true, false, "[1.0.0 1.1.0]"},
+		{false, true, "[1.1.0 1.2.0 1.2.0+b]"},
+		{true, true, "[1.0.0 1.1.0 1.2.0 1.2.0+b]"},
+	}
+	for _, tt := range tests {
+		if got := fmt.Sprint(Range(vs, from, to, tt.incFrom, tt.incTo)); got != tt.want {
+			t.Errorf("incFrom %v, incTo %v: got %s, want %s", tt.incFrom, tt.incTo, got, tt.want)
+		}
+	}
+}
+
+func TestCompareRaw(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"99999999999999999999.0.0", "100000000000000000000.0.0", -1},
+		{"100000000000000000000.0.0", "99999999999999999999.0.0", 1},
+		{"1.18446744073709551616.0", "1.18446744073709551616.0", 0},
+		{"1.0.0", "01.0.0", 0},
+		{"1.0.0-rc.1", "1.0.0", -1},
+		{"1.0.0+b", "1.0.0", 0},
+	}
+	for _, tt := range tests {
+		got, err := CompareRaw(tt.a, tt.b)
+		if err != nil || got != tt.want {
+			t.Errorf("CompareRaw(%s, %s): got %d, %v, want %d", tt.a, tt.b, got, err, tt.want)
+		}
+	}
+	if _, err := CompareRaw("1.0", "1.0.0"); err == nil {
+		t.Error("CompareRaw(1.0, 1.0.0): want error")
+	}
+}
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		warn []string
+	}{
+		{"01.2.3", "1.2.3", []string{`major number "01" has a leading zero`}},
+		{"1.2.3-01", "1.2.3-01", []string{`prerelease identifier "01" has a leading zero`}},
+		{"1.2.3-rc..1", "1.2.3-rc.1", []string{"empty prerelease identifier"}},
+		{"1.2.3+001", "1.2.3+001", nil},
+		{"1.2", "<nil>", []string{`invalid version core "1.2"`}},
+		{"1.2.3-β", "1.2.3-β", []string{`prerelease identifier "β" has invalid characters`}},
+		{"2.0.0-Ωmega.٣+a_b", "2.0.0-Ωmega.٣+a_b", []string{
+			`prerelease identifier "Ωmega" has invalid characters`,
+			`prerelease identifier "٣" has invalid characters`,
+			`build identifier "a_b" has invalid characters`,
+		}},
+	}
+	for _, tt := range tests {
+		v, warn := Lint(tt.in)
+		if fmt.Sprint(v) != tt.want || fmt.Sprint(warn) != fmt.Sprint(tt.warn) {
+			t.Errorf("%q: got %v, %q, want %s, %q", tt.in, v, warn, tt.want, tt.warn)
+		}
+	}
+}
+
+func TestEqualWithBuild(t *testing.T) {
+	tests := []struct {
+		a, b           string
+		equal, eqBuild bool
+	}{
+		{"1.2.3+build.1", "1.2.3+build.1", true, true},
+		{"1.2.3+build.1", "1.2.3+build.2", true, false},
+		{"1.2.3+build.1", "1.2.3", true, false},
+		{"1.2.3", "1.2.4", false, false},
+	}
+	for _, tt := range tests {
+		a, b := mustParse(tt.a), mustParse(tt.b)
+		if got := a.Equal(b); got != tt.equal {
+			t.Errorf("%s.Equal(%s): got %v", tt.a, tt.b, got)
+		}
+		if got := a.EqualWithBuild(b); got != tt.eqBuild {
+			t.Errorf("%s.EqualWithBuild(%s): got %v", tt.a, tt.b, got)
+		}
+	}
+}
+
+func TestComponents(t *testing.T) {
+	v := mustParse("1.2.3-rc.1+b")
+	if major, minor, patch := v.Components(); major != 1 || minor != 2 || patch != 3 {
+		t.Errorf("Components: got %d, %d, %d", major, minor, patch)
+	}
+	if k := v.CoreKey(); k != [3]int{1, 2, 3} {
+		t.Errorf("CoreKey: got %v", k)
+	}
+}
+
+func TestParseEpoch(t *testing.T) {
+	tests := []struct {
+		in    string
+		epoch int
+		want  string
+	}{
+		{"1:1.2.3", 1, "1:1.2.3"},
+		{"1.2.3", 0, "1.2.3"},
+		{"0:1.2.3-rc.1", 0, "1.2.3-rc.1"},
+	}
+	for _, tt := range tests {
+		v, err := ParseEpoch(tt.in)
+		if err != nil || v.Epoch != tt.epoch || v.String() != tt.want {
+			t.Errorf("%q: got %v, %v", tt.in, v, err)
+		}
+	}
+	for _, s := range []string{"x:1.2.3", "1:", "1:1.2"} {
+		if _, err := ParseEpoch(s); err == nil {
+			t.Errorf("%q: want error", s)
+		}
+	}
+	lo, _ := ParseEpoch("2:1.0.0")
+	hi, _ := ParseEpoch("1:3.0.0")
+	if !hi.Less(lo) || hi.Compare(lo) != -1 || lo.Equal(hi) {
+		t.Errorf("%v does not outrank %v", lo, hi)
+	}
+	if !mustParse("9.0.0").Less(hi) {
+		t.Errorf("epoch 0 does not precede epoch 1")
+	}
+}
+
+func TestCompareWith(t *testing.T) {
+	v, w := mustParse("1.2.3"), mustParse("1.2.3-rc.1")
+	if got := v.CompareWith(w, CompareOpts{}); got != 1 {
+		t.Errorf("default: got %d, want 1", got)
+	}
+	inv := CompareOpts{PrereleaseHigherPrecedence: true}
+	if got := v.CompareWith(w, inv); got != -1 {
+		t.Errorf("inverted: got %d, want -1", got)
+	}
+	if got := w.CompareWith(v, inv); got != 1 {
+		t.Errorf("inverted, swapped: got %d, want 1", got)
+	}
+	if got := mustParse("1.2.3-rc.1").CompareWith(mustParse("1.2.3-rc.2"), inv); got != -1 {
+		t.Errorf("inverted, two prereleases: got %d, want -1", got)
+	}
+}
+
+func TestBumpCounter(t *testing.T) {
+	max := strconv.Itoa(math.MaxInt)
+	tests := []struct {
+		in, want string
+	}{
+		{"1.2.3", "1.2.3-build.1"},
+		{"1.2.3-build.1", "1.2.3-build.2"},
+		{"1.2.3-build.9+x", "1.2.3-build.10"},
+		{"1.2.3-rc.1", "1.2.3-rc.1.build.1"},
+		{"1.2.3-build", "1.2.3-build.build.1"},
+		{"1.2.3-build." + max, "1.2.3-build." + max + ".build.1"},
+	}
+	for _, tt := range tests {
+		v := mustParse(tt.in)
+		got := v.BumpCounter("build")
+		if got.String() != tt.want {
+			t.Errorf("%s: got %v, want %s", tt.in, got, tt.want)
+		}
+		if _, err := Parse(got.String()); err != nil {
+			t.Errorf("%s: %v", tt.in, err)
+		}
+		if v.String() != tt.in {
+			t.Errorf("%s: modified to %v", tt.in, v)
+		}
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"1.2.3-rc.1", "Major: 1\nMinor: 2\nPatch: 3\nPrerelease: rc.1\nBuild: (none)"},
+		{"1.2.3+b.7", "Major: 1\nMinor: 2\nPatch: 3\nPrerelease: (none)\nBuild: b.7"},
+	}
+	for _, tt := range tests {
+		if got := mustParse(tt.in).Describe(); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.in, got, tt.want)
+		}
+	}
+	v, _ := ParseEpoch("2:1.0.0")
+	if got, want := v.Describe(), "Epoch: 2\nMajor: 1\nMinor: 0\nPatch: 0\nPrerelease: (none)\nBuild: (none)"; got != want {
+		t.Errorf("epoch: got %q, want %q", got, want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		in    string
+		empty bool
+	}{
+		{"", true},
+		{" \t\n", true},
+		{"x", false},
+		{"1.2.x", false},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.in)
+		if !errors.Is(err, ErrInvalid) {
+			t.Errorf("%q: %v is not ErrInvalid", tt.in, err)
+		}
+		if errors.Is(err, ErrEmpty) != tt.empty {
+			t.Errorf("%q: errors.Is(%v, ErrEmpty) != %v", tt.in, err, tt.empty)
+		}
+	}
+	if !errors.Is(ErrEmpty, ErrInvalid) {
+		t.Error("ErrEmpty is not ErrInvalid")
+	}
+}
+
+func TestPrevious(t *testing.T) {
+	vs := parseAll("1.2.0", "1.0.0", "1.1.0", "2.0.0-rc.1")
+	tests := []struct {
+		v, want string
+	}{
+		{"1.1.0", "1.0.0"},
+		{"1.0.0", "<nil>"},
+		{"1.1.5", "1.1.0"},
+		{"2.0.0", "2.0.0-rc.1"},
+		{"1.2.0+b", "1.1.0"},
+	}
+	for _, tt := range tests {
+		if got := fmt.Sprint(Previous(vs, mustParse(tt.v))); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	vs := parseAll("1.2.0", "1.0.0", "1.1.0", "2.0.0-rc.1")
+	tests := []struct {
+		v, want string
+	}{
+		{"1.1.0", "1.2.0"},
+		{"2.0.0-rc.1", "<nil>"},
+		{"1.0.5", "1.1.0"},
+		{"1.0.0+b", "1.1.0"},
+	}
+	for _, tt := range tests {
+		if got := fmt.Sprint(Next(vs, mustParse(tt.v))); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestIndexSorted(t *testing.T) {
+	vs := parseAll("1.0.0", "1.1.0-rc.1", "1.1.0", "1.2.0")
+	index := IndexSorted(vs)
+	tests := []struct {
+		v  string
+		i  int
+		ok bool
+	}{
+		{"1.0.0", 0, true},
+		{"1.1.0", 2, true},
+		{"1.1.0+b", 2, true},
+		{"1.2.0", 3, true},
+		{"0.9.0", 0, false},
+		{"1.1.5", 3, false},
+		{"2.0.0", 4, false},
+	}
+	for _, tt := range tests {
+		if i, ok := index(mustParse(tt.v)); i != tt.i || ok != tt.ok {
+			t.Errorf("%s: got %d, %v, want %d, %v", tt.v, i, ok, tt.i, tt.ok)
+		}
+	}
+}
+
+func densePatches(n int) []*Version {
+	vs := make([]*Version, n)
+	for i := range vs {
+		vs[i] = &Version{Major: 1, Minor: 2, Patch: i}
+	}
+	return vs
+}
+
+func BenchmarkIndexSorted(b *testing.B) {
+	vs := densePatches(10000)
+	index := IndexSorted(vs)
+	v := &Version{Major: 1, Minor: 2, Patch: 7777}
+	for i := 0; i < b.N; i++ {
+		index(v)
+	}
+}
+
+func BenchmarkIndexLinear(b *testing.B) {
+	vs := densePatches(10000)
+	v := &Version{Major: 1, Minor: 2, Patch: 7777}
+	for i := 0; i < b.N; i++ {
+		for _, w := range vs {
+			if w.Compare(v) == 0 {
+				break
+			}
+		}
+	}
+}
+
+func TestDeepEqual(t *testing.T) {
+	tests := []struct {
+		a, b             *Version
+		deepEqual, equal bool
+	}{
+		{mustParse("1.2.3-rc.1+b"), mustParse("1.2.3-rc.1+b"), true, true},
+		{&Version{Major: 1}, &Version{Major: 1, Prerelease: []string{}}, false, true},
+		{&Version{Major: 1}, &Version{Major: 1, Build: []string{}}, false, true},
+		{mustParse("1.2.3+a"), mustParse("1.2.3+b"), false, true},
+		{mustParse("1.2.3"), mustParse("1.2.4"), false, false},
+	}
+	for _, tt := range tests {
+		if got := tt.a.DeepEqual(tt.b); got != tt.deepEqual {
+			t.Errorf("%#v.DeepEqual(%#v): got %v", tt.a, tt.b, got)
+		}
+		if got := tt.a.Equal(tt.b); got != tt.equal {
+			t.Errorf("%#v.Equal(%#v): got %v", tt.a, tt.b, got)
+		}
+	}
+}
+
+func TestCompareBuildLexical(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.2.3+01", "1.2.3+1", -1},
+		{"1.2.3+10", "1.2.3+9", -1},
+		{"1.2.3+b", "1.2.3+a", 1},
+		{"1.2.3+a", "1.2.3+a.1", -1},
+		{"1.2.3+a", "1.2.3+a", 0},
+		{"1.2.3+z", "1.2.4+a", -1},
+	}
+	for _, tt := range tests {
+		if got := mustParse(tt.a).CompareBuildLexical(mustParse(tt.b)); got != tt.want {
+			t.Errorf("%s, %s: got %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestIsSorted(t *testing.T) {
+	tests := []struct {
+		in   []string
+		want bool
+	}{
+		{[]string{"1.0.0", "1.1.0", "2.0.0"}, true},
+		{[]string{"1.0.0", "1.0.0", "1.1.0"}, true},
+		{[]string{"1.0.0+b", "1.0.0+a"}, true},
+		{[]string{"1.1.0", "1.0.0"}, false},
+		{[]string{"1.0.0", "1.0.0-rc.1"}, false},
+		{nil, true},
+	}
+	for _, tt := range tests {
+		if got := IsSorted(parseAll(tt.in...)); got != tt.want {
+			t.Errorf("%v: got %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseTrim(t *testing.T) {
+	for _, s := range []string{" 1.2.3", "1.2.3 ", "\t1.2.3\t", " 1.2.3\n", "\uFEFF1.2.3\r\n"} {
+		if v, err := ParseTrim(s); err != nil || v.String() != "1.2.3" {
+			t.Errorf("%q: got %v, %v", s, v, err)
+		}
+	}
+	for _, s := range []string{"1.2 .3", "1.2.3 -rc", " ", ""} {
+		if _, err := ParseTrim(s); err == nil {
+			t.Errorf("%q: want error", s)
+		}
+	}
+}
+
+func TestLess(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"1.2.3", "1.2.3", false},
+		{"1.2.3", "1.2.4", true},
+		{"1.2.4", "1.2.3", false},
+		{"1.2.3-rc.1", "1.2.3", true},
+		{"1.2.3", "1.2.3-rc.1", false},
+		{"1.2.3", "1.2.3+b", true},
+		{"1.2.3+b", "1.2.3", false},
+		{"1.2.3+1", "1.2.3+2", true},
+	}
+	for _, tt := range tests {
+		if got := mustParse(tt.a).Less(mustParse(tt.b)); got != tt.want {
+			t.Errorf("%s.Less(%s): got %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func BenchmarkLessPlain(b *testing.B) {
+	vs := parseAll("1.2.3", "1.2.3", "1.2.4", "2.0.0")
+	for i := 0; i < b.N; i++ {
+		vs[i%4].Less(vs[(i+1)%4])
+	}
+}
+
+func TestCanInc(t *testing.T) {
+	v := &Version{Major: math.MaxInt, Minor: math.MaxInt - 1, Patch: 999999999}
+	tests := []struct {
+		k    Kind
+		want bool
+	}{
+		{KindMajor, false},
+		{KindMinor, true},
+		{KindPatch, true},
+		{Kind(-1), false},
+	}
+	for _, tt := range tests {
+		if got := v.CanInc(tt.k); got != tt.want {
+			t.Errorf("kind %d: got %v, want %v", tt.k, got, tt.want)
+		}
+	}
+}
+
+func TestParseNoBuild(t *testing.T) {
+	for _, s := range []string{"1.2.3", "1.2.3-rc.1"} {
+		if v, err := ParseNoBuild(s); err != nil || v.String() != s {
+			t.Errorf("%q: got %v, %v", s, v, err)
+		}
+	}
+	for _, s := range []string{"1.2.3+b", "1.2.3-rc.1+b", "x"} {
+		if _, err := ParseNoBuild(s); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%q: got %v, want ErrInvalid", s, err)
+		}
+	}
+}
+
+func TestMergeBuild(t *testing.T) {
+	v, other := mustParse("1.2.3+a.b"), mustParse("0.0.0+b.c.c")
+	if got := v.MergeBuild(other); got.String() != "1.2.3+a.b.c" {
+		t.Errorf("got %v, want 1.2.3+a.b.c", got)
+	}
+	if v.String() != "1.2.3+a.b" || other.String() != "0.0.0+b.c.c" {
+		t.Errorf("modified: %v, %v", v, other)
+	}
+	if got := mustParse("1.2.3").MergeBuild(mustParse("1.0.0")); got.Build != nil {
+		t.Errorf("got build %q, want nil", got.Build)
+	}
+}
+
+func TestParseGoModule(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"v2.0.0+incompatible", "2.0.0+incompatible"},
+		{"2.0.0+incompatible", "2.0.0+incompatible"},
+		{"v1.2.3", "1.2.3"},
+		{"v1.2.3-pre.1", "1.2.3-pre.1"},
+	}
+	for _, tt := range tests {
+		v, err := ParseGoModule(tt.in)
+		if err != nil || v.String() != tt.want {
+			t.Errorf("%q: got %v, %v", tt.in, v, err)
+		}
+	}
+	v, _ := ParseGoModule("v2.0.0+incompatible")
+	if v.Compare(mustParse("2.0.0")) != 0 {
+		t.Errorf("%v does not compare as 2.0.0", v)
+	}
+	for _, s := range []string{"v1.0.0+incompatible", "v1.2", "vx"} {
+		if _, err := ParseGoModule(s); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%q: got %v, want ErrInvalid", s, err)
+		}
+	}
+}
+
+func TestParseValid(t *testing.T) {
+	lines := []string{"1.0.0", "main", "v1.1.0", "1.2.0-rc.1", "", "release/2.0", "2.0.0+b", "junk line"}
+	if got := fmt.Sprint(ParseValid(lines)); got != "[1.0.0 1.2.0-rc.1 2.0.0+b]" {
+		t.Errorf("got %s", got)
+	}
+	if got := ParseValid([]string{"x"}); len(got) != 0 {
+		t.Errorf("got %v, want none", got)
+	}
+}
+
+func TestTrain(t *testing.T) {
+	tests := []struct {
+		in, train, major string
+	}{
+		{"1.2.3", "1.2.x", "1.x"},
+		{"1.2.3-rc.1+b", "1.2.x", "1.x"},
+		{"0.10.0", "0.10.x", "0.x"},
+	}
+	for _, tt := range tests {
+		v := mustParse(tt.in)
+		if got := v.Train(); got != tt.train {
+			t.Errorf("%s.Train(): got %s, want %s", tt.in, got, tt.train)
+		}
+		if got := v.MajorTrain(); got != tt.major {
+			t.Errorf("%s.MajorTrain(): got %s, want %s", tt.in, got, tt.major)
+		}
+	}
+}
+
+func TestVersionValues(t *testing.T) {
+	var vs VersionValues
+	for _, v := range parseAll("1.2.0", "1.0.0-rc.1", "2.0.0", "1.0.0") {
+		vs = append(vs, *v)
+	}
+	sort.Sort(vs)
+	if got := fmt.Sprint([]Version(vs)); got != "[1.0.0-rc.1 1.0.0 1.2.0 2.0.0]" {
+		t.Errorf("got %s", got)
+	}
+}
+
+func TestCompareStrings(t *testing.T) {
+	tests := []struct {
+		a, op, b string
+		want     bool
+	}{
+		{"1.2.3", ">", "1.2.2", true},
+		{"1.2.3", ">", "1.2.3", false},
+		{"1.2.3", ">=", "1.2.3", true},
+		{"1.2.3", "<", "1.2.3-rc.1", false},
+		{"1.2.3-rc.1", "<", "1.2.3", true},
+		{"1.2.3", "<=", "1.2.3+b", true},
+		{"1.2.3", "==", "1.2.3+b", true},
+		{"1.2.3", "!=", "1.2.4", true},
+		{"1.2.3", "!=", "1.2.3", false},
+	}
+	for _, tt := range tests {
+		got, err := CompareStrings(tt.a, tt.op, tt.b)
+		if err != nil || got != tt.want {
+			t.Errorf("%s %s %s: got %v, %v, want %v", tt.a, tt.op, tt.b, got, err, tt.want)
+		}
+	}
+	if _, err := CompareStrings("1.2.3", "=>", "1.2.3"); err == nil {
+		t.Error("invalid operator: want error")
+	}
+	if _, err := CompareStrings("1.2", "<", "1.2.3"); !errors.Is(err, ErrInvalid) {
+		t.Errorf("invalid version: got %v, want ErrInvalid", err)
+	}
+}
+
+func TestIsProductionReady(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{"0.9.0", false},
+		{"1.0.0-rc.1", false},
+		{"1.0.0+b", false},
+		{"1.0.0", true},
+		{"2.3.4", true},
+	}
+	for _, tt := range tests {
+		if got := mustParse(tt.in).IsProductionReady(); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseExtended(t *testing.T) {
+	for _, s := range []string{"1.2.3", "1.2.3.4", "1.2.3.4.5-rc.1+b"} {
+		if v, err := ParseExtended(s); err != nil || v.String() != s {
+			t.Errorf("%q: got %v, %v", s, v, err)
+		}
+	}
+	if _, err := Parse("1.2.3.4"); err == nil {
+		t.Error("Parse(1.2.3.4): want error")
+	}
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.2.3.4", "1.2.3.5", -1},
+		{"1.2.3.5", "1.2.3.4", 1},
+		{"1.2.3", "1.2.3.0", -1},
+		{"1.2.3.4", "1.2.4", -1},
+		{"1.2.3.4-rc.1", "1.2.3.4", -1},
+		{"1.2.3.4", "1.2.3.4", 0},
+	}
+	for _, tt := range tests {
+		a, _ := ParseExtended(tt.a)
+		b, _ := ParseExtended(tt.b)
+		if got := a.Compare(b); got != tt.want {
+			t.Errorf("%s.Compare(%s): got %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+		if got := a.Less(b); got != (tt.want < 0) {
+			t.Errorf("%s.Less(%s): got %v", tt.a, tt.b, got)
+		}
+		if got := a.Equal(b); got != (tt.want == 0) {
+			t.Errorf("%s.Equal(%s): got %v", tt.a, tt.b, got)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, s := range []string{"1.2.3", "01.02.03-01.α+001", "1.2.3-a-b.c+d"} {
+		if ok, err := RoundTrip(s); !ok || err != nil {
+			t.Errorf("%q: got %v, %v", s, ok, err)
+		}
+	}
+	for _, s := range []string{"0.0.d", "١.٢.٣", "1.2"} {
+		if _, err := RoundTrip(s); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%q: got %v, want ErrInvalid", s, err)
+		}
+	}
+}
+
+func FuzzParse(f *testing.F) {
+	for _, s := range []string{"1.2.3", "0.0.d", "١.٢.٣", "01.02.03-β.01+001", "1.2.3-a-b.c+d", ""} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		ok, err := RoundTrip(s)
+		if err != nil {
+			if !errors.Is(err, ErrInvalid) {
+				t.Fatalf("%q: %v", s, err)
+			}
+			return
+		}
+		if !ok {
+			v, _ := Parse(s)
+			t.Fatalf("%q: %#v does not round trip through %q", s, v, v.String())
+		}
+	})
+}
+
+func TestWithinPatch(t *testing.T) {
+	tests := []struct {
+		a, b      string
+		tolerance int
+		want      bool
+	}{
+		{"1.2.3", "1.2.5", 2, true},
+		{"1.2.5", "1.2.3", 2, true},
+		{"1.2.3", "1.2.6", 2, false},
+		{"1.2.3", "1.2.3", 0, true},
+		{"1.2.3-rc.1", "1.2.4+b", 1, true},
+		{"1.2.3", "1.3.3", 5, false},
+		{"1.2.3", "2.2.3", 5, false},
+	}
+	for _, tt := range tests {
+		if got := mustParse(tt.a).WithinPatch(mustParse(tt.b), tt.tolerance); got != tt.want {
+			t.Errorf("%s, %s, %d: got %v, want %v", tt.a, tt.b, tt.tolerance, got, tt.want)
+		}
+	}
+}
+
+func TestJoin(t *testing.T) {
+	vs := parseAll("1.2.0", "1.0.0", "2.0.0-rc.1")
+	if got := Join(vs, ", "); got != "1.0.0, 1.2.0, 2.0.0-rc.1" {
+		t.Errorf("Join: got %q", got)
+	}
+	if got := JoinDesc(vs, "|"); got != "2.0.0-rc.1|1.2.0|1.0.0" {
+		t.Errorf("JoinDesc: got %q", got)
+	}
+	if got := fmt.Sprint(vs); got != "[1.2.0 1.0.0 2.0.0-rc.1]" {
+		t.Errorf("input modified: %s", got)
+	}
+	if got := Join(nil, ","); got != "" {
+		t.Errorf("Join(nil): got %q", got)
+	}
+}
+
+func TestIsEvenMinor(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{"1.2.0", true},
+		{"1.3.0", false},
+		{"1.0.0", true},
+		{"2.5.1-rc.1", false},
+	}
+	for _, tt := range tests {
+		if got := mustParse(tt.in).IsEvenMinor(); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestPrereleaseStage(t *testing.T) {
+	tests := []struct {
+		in     string
+		name   string
+		num    int
+		hasNum bool
+	}{
+		{"1.0.0-rc.2", "rc", 2, true},
+		{"1.0.0-beta", "beta", 0, false},
+		{"1.0.0-alpha.1.2", "alpha", 0, false},
+		{"1.0.0-alpha.x", "alpha", 0, false},
+		{"1.0.0", "", 0, false},
+	}
+	for _, tt := range tests {
+		name, num, hasNum := mustParse(tt.in).PrereleaseStage()
+		if name != tt.name || num != tt.num || hasNum != tt.hasNum {
+			t.Errorf("%s: got %q, %d, %v", tt.in, name, num, hasNum)
+		}
+	}
+}
+
+func TestIsNextAfter(t *testing.T) {
+	tests := []struct {
+		current, candidate string
+		want               bool
+	}{
+		{"1.2.3", "1.2.4", true},
+		{"1.2.3", "1.2.3", false},
+		{"1.2.3", "1.2.3+b", false},
+		{"1.2.3-rc.1", "1.2.3", true},
+		{"1.2.3", "1.2.2", false},
+	}
+	for _, tt := range tests {
+		if got := IsNextAfter(mustParse(tt.current), mustParse(tt.candidate)); got != tt.want {
+			t.Errorf("%s, %s: got %v, want %v", tt.current, tt.candidate, got, tt.want)
+		}
+	}
+}
+
+func TestValidateMonotonic(t *testing.T) {
+	if err := ValidateMonotonic(parseAll("1.0.0", "1.1.0-rc.1", "1.1.0", "2.0.0")); err != nil {
+		t.Errorf("increasing: %v", err)
+	}
+	err := ValidateMonotonic(parseAll("1.0.0", "1.2.0", "1.1.0", "2.0.0", "1.0.0"))
+	if err == nil || err.Error() != "version 1.1.0 at index 2 does not follow 1.2.0" {
+		t.Errorf("regression: got %v", err)
+	}
+	if err := ValidateMonotonic(parseAll("1.0.0", "1.0.0+b")); err == nil {
+		t.Error("equal precedence: want error")
+	}
+}
+
+// invertedPrerelease is a Comparer that orders a version without a
+// prerelease before its prereleases.
+type invertedPrerelease struct{}
+
+func (invertedPrerelease) Compare(a, b *Version) int {
+	return a.CompareWith(b, CompareOpts{PrereleaseHigherPrecedence: true})
+}
+
+func TestComparer(t *testing.T) {
+	in := []string{"1.2.3-rc.1", "1.2.3", "1.2.2", "1.2.3-beta"}
+	tests := []struct {
+		name   string
+		c      Comparer
+		sorted string
+		max    string
+	}{
+		{"nil", nil, "[1.2.2 1.2.3-beta 1.2.3-rc.1 1.2.3]", "1.2.3"},
+		{"default", DefaultComparer, "[1.2.2 1.2.3-beta 1.2.3-rc.1 1.2.3]", "1.2.3"},
+		{"inverted", invertedPrerelease{}, "[1.2.2 1.2.3 1.2.3-beta 1.2.3-rc.1]", "1.2.3-rc.1"},
+	}
+	for _, tt := range tests {
+		vs := parseAll(in...)
+		SortWith(vs, tt.c)
+		if got := fmt.Sprint(vs); got != tt.sorted {
+			t.Errorf("%s: SortWith: got %s, want %s", tt.name, got, tt.sorted)
+		}
+		if got := fmt.Sprint(MaxWith(parseAll(in...), tt.c)); got != tt.max {
+			t.Errorf("%s: MaxWith: got %s, want %s", tt.name, got, tt.max)
+		}
+	}
+	if MaxWith(nil, nil) != nil {
+		t.Error("MaxWith(nil): want nil")
+	}
+}
+
+func TestStringUnicodeRoundTrip(t *testing.T) {
+	tests := []struct {
+		in         string
+		prerelease []string
+		build      []string
+	}{
+		{"3.24.3-β+20150115102400", []string{"β"}, []string{"20150115102400"}},
+		{"1.0.0-αβγ.ü-x", []string{"αβγ", "ü-x"}, nil},
+		{"1.0.0+ñ.ß.日本", nil, []string{"ñ", "ß", "日本"}},
+		{"2.0.0-Ωmega.٣+Straße", []string{"Ωmega", "٣"}, []string{"Straße"}},
+	}
+	for _, tt := range tests {
+		v := mustParse(tt.in)
+		if fmt.Sprint(v.Prerelease) != fmt.Sprint(tt.prerelease) || fmt.Sprint(v.Build) != fmt.Sprint(tt.build) {
+			t.Errorf("%s: got %q, %q", tt.in, v.Prerelease, v.Build)
+		}
+		if got := v.String(); got != tt.in {
+			t.Errorf("%s: String(): got %q", tt.in, got)
+		}
+		if w := mustParse(v.String()); !w.DeepEqual(v) {
+			t.Errorf("%s: reparsed as %#v", tt.in, w)
+		}
+	}
+}
+
+func TestPrecedenceChain(t *testing.T) {
+	chain := parseAll(
+		"1.0.0-alpha",
+		"1.0.0-alpha.1",
+		"1.0.0-alpha.beta",
+		"1.0.0-beta",
+		"1.0.0-beta.2",
+		"1.0.0-beta.11",
+		"1.0.0-rc.1",
+		"1.0.0",
+	)
+	for i := 1; i < len(chain); i++ {
+		a, b := chain[i-1], chain[i]
+		if !a.Less(b) || b.Less(a) {
+			t.Errorf("Less: %v is not before %v", a, b)
+		}
+		if a.Compare(b) != -1 || b.Compare(a) != 1 {
+			t.Errorf("Compare: %v is not before %v", a, b)
+		}
+	}
+	for i, a := range chain {
+		for j, b := range chain {
+			if got, want := a.Compare(b), intCmp(i, j); got != want {
+				t.Errorf("%v.Compare(%v): got %d, want %d", a, b, got, want)
+			}
+		}
+	}
+}
+
+func TestLatestPerMajor(t *testing.T) {
+	vs := parseAll("1.0.0", "1.4.2", "1.5.0-rc.1", "2.0.0", "2.1.0-beta", "3.0.0-rc.1", "0.9.0")
+	got := LatestPerMajor(vs)
+	want := map[int]string{0: "0.9.0", 1: "1.4.2", 2: "2.0.0"}
+	if len(got) != len(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	for k, w := range want {
+		if s := fmt.Sprint(got[k]); s != w {
+			t.Errorf("major %d: got %s, want %s", k, s, w)
+		}
+	}
+}
+
+func TestLatestPerMinor(t *testing.T) {
+	vs := parseAll("1.0.0", "1.0.3", "1.1.0", "1.1.1-rc.1", "2.0.0-rc.1")
+	got := LatestPerMinor(vs)
+	want := map[[2]int]string{{1, 0}: "1.0.3", {1, 1}: "1.1.0"}
+	if len(got) != len(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	for k, w := range want {
+		if s := fmt.Sprint(got[k]); s != w {
+			t.Errorf("%v: got %s, want %s", k, s, w)
+		}
+	}
+}
+
+func TestParseGoQuery(t *testing.T) {
+	vs := parseAll("1.2.0", "1.2.5", "1.3.0", "1.4.0-rc.1")
+	tests := []struct {
+		query, current, want string
+	}{
+		{"@latest", "1.2.1", "1.3.0"},
+		{"latest", "1.5.0-rc.1", "1.3.0"},
+		{"@upgrade", "1.2.1", "1.3.0"},
+		{"@upgrade", "1.5.0-rc.1", "1.5.0-rc.1"},
+		{"@patch", "1.2.1", "1.2.5"},
+		{"@patch", "1.3.1", "1.3.1"},
+		{"@v1.2.0", "1.3.0", "1.2.0"},
+		{"@v9.9.9", "1.3.0", "<nil>"},
+	}
+	for _, tt := range tests {
+		q, err := ParseGoQuery(tt.query)
+		if err != nil {
+			t.Errorf("%s: %v", tt.query, err)
+			continue
+		}
+		if got := fmt.Sprint(q.Resolve(mustParse(tt.current), vs)); got != tt.want {
+			t.Errorf("%s from %s: got %s, want %s", tt.query, tt.current, got, tt.want)
+		}
+	}
+	q, _ := ParseGoQuery("@patch")
+	if got := fmt.Sprint(q.Resolve(nil, vs)); got != "1.3.0" {
+		t.Errorf("@patch without current: got %s", got)
+	}
+	q, _ = ParseGoQuery("@latest")
+	if got := fmt.Sprint(q.Resolve(nil, parseAll("1.0.0-rc.1", "1.0.0-rc.2"))); got != "1.0.0-rc.2" {
+		t.Errorf("@latest with only prereleases: got %s", got)
+	}
+	for _, s := range []string{"1.2.0", "@v1.2", "@newest", ""} {
+		if _, err := ParseGoQuery(s); err == nil {
+			t.Errorf("%q: want error", s)
+		}
+	}
+}
+
+func TestCompatibleUpperBound(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"1.2.3", "2.0.0"},
+		{"1.2.3-rc.1+b", "2.0.0"},
+		{"0.2.3", "0.3.0"},
+		{"0.0.3", "0.0.4"},
+		{"0.0.0", "0.0.1"},
+	}
+	for _, tt := range tests {
+		if got := mustParse(tt.in).CompatibleUpperBound().String(); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestEqualCoreBuild(t *testing.T) {
+	tests := []struct {
+		a, b                        string
+		coreBuild, equal, withBuild bool
+	}{
+		{"1.2.3-rc.1+b", "1.2.3-rc.2+b", true, false, false},
+		{"1.2.3+b", "1.2.3-rc.1+b", true, false, false},
+		{"1.2.3+a", "1.2.3+b", false, true, false},
+		{"1.2.3+b", "1.2.3+b", true, true, true},
+		{"1.2.3", "1.2.4", false, false, false},
+	}
+	for _, tt := range tests {
+		a, b := mustParse(tt.a), mustParse(tt.b)
+		if got := a.EqualCoreBuild(b); got != tt.coreBuild {
+			t.Errorf("%s.EqualCoreBuild(%s): got %v", tt.a, tt.b, got)
+		}
+		if got := a.Equal(b); got != tt.equal {
+			t.Errorf("%s.Equal(%s): got %v", tt.a, tt.b, got)
+		}
+		if got := a.EqualWithBuild(b); got != tt.withBuild {
+			t.Errorf("%s.EqualWithBuild(%s): got %v", tt.a, tt.b, got)
+		}
+	}
+}
+
+func TestIndex(t *testing.T) {
+	vs := parseAll("1.0.0", "1.1.0+b", "1.2.0-rc.1")
+	tests := []struct {
+		target string
+		want   int
+	}{
+		{"1.0.0", 0},
+		{"1.1.0", 1},
+		{"1.1.0+other", 1},
+		{"1.2.0-rc.1", 2},
+		{"1.2.0", -1},
+	}
+	for _, tt := range tests {
+		target := mustParse(tt.target)
+		if got := Index(vs, target); got != tt.want {
+			t.Errorf("Index(%s): got %d, want %d", tt.target, got, tt.want)
+		}
+		if got := Contains(vs, target); got != (tt.want >= 0) {
+			t.Errorf("Contains(%s): got %v", tt.target, got)
+		}
+	}
+}
+
+func TestSortKey(t *testing.T) {
+	var vs []*Version
+	for _, s := range []string{
+		"0.0.0", "1.0.0-0", "1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta",
+		"1.0.0-beta", "1.0.0-beta.2", "1.0.0-beta.11", "1.0.0-beta.011", "1.0.0-a-b",
+		"1.0.0-ab", "1.0.0-β", "1.0.0-rc.1", "1.0.0", "1.0.0+b", "1.0.0.0",
+		"1.0.0.0-a", "1.0.0.1", "1.10.0", "10.0.0", "1:0.0.0",
+	} {
+		v, err := parseMixed(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		vs = append(vs, v)
+	}
+	for _, a := range vs {
+		for _, b := range vs {
+			if got, want := bytes.Compare(a.SortKey(), b.SortKey()), a.Compare(b); got != want {
+				t.Errorf("%v, %v: bytes.Compare gives %d, Compare gives %d", a, b, got, want)
+			}
+		}
+	}
+}
+
+// parseMixed parses s with ParseExtended, or with ParseEpoch if s has
+// an epoch.
+func parseMixed(s string) (*Version, error) {
+	if v, err := ParseExtended(s); err == nil {
+		return v, nil
+	}
+	return ParseEpoch(s)
+}
+
+func TestParsePrefix(t *testing.T) {
+	tests := []struct {
+		in, v, rest string
+		err         bool
+	}{
+		{"1.2.3", "1.2.3", "", false},
+		{"1.2.3 and more", "1.2.3", " and more", false},
+		{"1.2.3-rc.1+b5, next", "1.2.3-rc.1+b5", ", next", false},
+		{"1.2.3_x", "1.2.3", "_x", false},
+		{"1.2.3. Next", "1.2.3", ". Next", false},
+		{"1.2.3.", "1.2.3", ".", false},
+		{"1.2.3+ x", "", "", true},
+		{"1.2.3- x", "", "", true},
+		{"1.2.3.4", "", "", true},
+		{"1.2.3-rc.1.x+b.", "1.2.3-rc.1.x+b", ".", false},
+		{"1.2.3456789012 x", "", "", true},
+		{"1234567890.2.3", "", "", true},
+		{"1.2", "", "", true},
+		{"v1.2.3", "", "", true},
+		{"", "", "", true},
+	}
+	for _, tt := range tests {
+		v, rest, err := ParsePrefix(tt.in)
+		if tt.err {
+			if err == nil || !errors.Is(err, ErrInvalid) || rest != tt.in {
+				t.Errorf("ParsePrefix(%q): got %v, %q, %v, want ErrInvalid", tt.in, v, rest, err)
+			}
+			continue
+		}
+		if err != nil || v.String() != tt.v || rest != tt.rest {
+			t.Errorf("ParsePrefix(%q): got %v, %q, %v, want %s, %q", tt.in, v, rest, err, tt.v, tt.rest)
+		}
+	}
+}
+
+func TestDuplicateCores(t *testing.T) {
+	var vs []*Version
+	for _, s := range []string{
+		"1.2.3", "1.2.3-rc.1", "1.2.3+b", "1:1.2.3", "1.2.3.4", "1.2.3.5", "1.2.3.4-a", "2.0.0",
+	} {
+		vs = append(vs, parseMixedMust(t, s))
+	}
+	m := DuplicateCores(vs)
+	want := map[string]string{
+		"1.2.3":   "[1.2.3 1.2.3-rc.1 1.2.3+b]",
+		"1.2.3.4": "[1.2.3.4 1.2.3.4-a]",
+	}
+	if len(m) != len(want) {
+		t.Errorf("DuplicateCores: got %v, want %v", m, want)
+	}
+	for k, w := range want {
+		if got := fmt.Sprint(m[k]); got != w {
+			t.Errorf("DuplicateCores[%q]: got %s, want %s", k, got, w)
+		}
+	}
+}
+
+func parseMixedMust(t *testing.T, s string) *Version {
+	t.Helper()
+	v, err := parseMixed(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestParseDetail(t *testing.T) {
+	tests := []struct {
+		in   string
+		v    string
+		info ParseInfo
+	}{
+		{"1.2.3", "1.2.3", ParseInfo{}},
+		{"v1.2.3", "1.2.3", ParseInfo{HadVPrefix: true}},
+		{"1.2.3-rc.1", "1.2.3-rc.1", ParseInfo{HadPrerelease: true}},
+		{"1.2.3+b5", "1.2.3+b5", ParseInfo{HadBuild: true}},
+		{"v1.2.3-0+b", "1.2.3-0+b", ParseInfo{true, true, true}},
+	}
+	for _, tt := range tests {
+		v, info, err := ParseDetail(tt.in)
+		if err != nil || v.String() != tt.v || info != tt.info {
+			t.Errorf("ParseDetail(%q): got %v, %+v, %v, want %s, %+v", tt.in, v, info, err, tt.v, tt.info)
+		}
+	}
+	for _, s := range []string{"1.2", "vv1.2.3", "V1.2.3", ""} {
+		if v, info, err := ParseDetail(s); !errors.Is(err, ErrInvalid) || v != nil || info != (ParseInfo{}) {
+			t.Errorf("ParseDetail(%q): got %v, %+v, %v, want ErrInvalid", s, v, info, err)
+		}
+	}
+}
+
+func TestChannel(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"1.2.3", "stable"},
+		{"1.2.3+b5", "stable"},
+		{"1.2.3-alpha", "alpha"},
+		{"1.2.3-dev.4", "alpha"},
+		{"1.2.3-beta.2", "beta"},
+		{"1.2.3-rc.1", "beta"},
+		{"1.2.3-nightly", "unknown"},
+		{"1.2.3-1.beta", "unknown"},
+	}
+	for _, tt := range tests {
+		if got := mustParse(tt.in).Channel(); got != tt.want {
+			t.Errorf("%s.Channel(): got %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestDefaultChannels(t *testing.T) {
+	m := DefaultChannels()
+	if len(m) != 4 || m["dev"] != "alpha" || m["rc"] != "beta" {
+		t.Errorf("got %v", m)
+	}
+	m["rc"] = "candidate"
+	m["nightly"] = "edge"
+	if got := mustParse("1.2.3-rc.1").Channel(); got != "beta" {
+		t.Errorf("Channel after modifying DefaultChannels(): got %q, want beta", got)
+	}
+	if got := mustParse("1.2.3-nightly").ChannelWith(m); got != "edge" {
+		t.Errorf("ChannelWith: got %q, want edge", got)
+	}
+	if got := DefaultChannels()["rc"]; got != "beta" {
+		t.Errorf("DefaultChannels()[rc]: got %q, want beta", got)
+	}
+}
+
+func TestChannelWith(t *testing.T) {
+	channels := map[string]string{"nightly": "edge", "rc": "candidate"}
+	tests := []struct {
+		in, want string
+	}{
+		{"1.2.3", "stable"},
+		{"1.2.3-nightly.20150115", "edge"},
+		{"1.2.3-rc.1", "candidate"},
+		{"1.2.3-beta", "unknown"},
+	}
+	for _, tt := range tests {
+		if got := mustParse(tt.in).ChannelWith(channels); got != tt.want {
+			t.Errorf("%s.ChannelWith(%v): got %q, want %q", tt.in, channels, got, tt.want)
+		}
+	}
+}
+
+func TestRank(t *testing.T) {
+	vs := parseAll("1.0.0", "1.0.0-rc.1", "2.0.0", "1.0.0+b", "0.9.0")
+	tests := []struct {
+		in   string
+		want int
+	}{
+		{"0.1.0", 0},
+		{"0.9.0", 0},
+		{"1.0.0-rc.1", 1},
+		{"1.0.0", 2},
+		{"1.0.0+other", 2},
+		{"1.5.0", 4},
+		{"2.0.0", 4},
+		{"3.0.0", 5},
+	}
+	for _, tt := range tests {
+		if got := Rank(vs, mustParse(tt.in)); got != tt.want {
+			t.Errorf("Rank(%v, %s): got %d, want %d", vs, tt.in, got, tt.want)
+		}
+	}
+	if got := Rank(nil, mustParse("1.0.0")); got != 0 {
+		t.Errorf("Rank(nil, 1.0.0): got %d, want 0", got)
+	}
+}
+
+func TestParseWithCharset(t *testing.T) {
+	tests := []struct {
+		in, extra string
+		want      string
+		err       bool
+	}{
+		{"1.2.3-my_build+ci_7", "_", "1.2.3-my_build+ci_7", false},
+		{"1.2.3-a~b", "_~", "1.2.3-a~b", false},
+		{"1.2.3-my_build", "", "", true},
+		{"1.2.3-my_build", "~", "", true},
+		{"1_2.3.4", "_", "", true},
+		{"1.2.3-a_b", "_.", "", true},
+		{"1.2.3-a+b", "+", "", true},
+	}
+	for _, tt := range tests {
+		v, err := ParseWithCharset(tt.in, tt.extra)
+		if tt.err {
+			if err == nil {
+				t.Errorf("ParseWithCharset(%q, %q): got %v, want error", tt.in, tt.extra, v)
+			}
+			continue
+		}
+		if err != nil || v.String() != tt.want {
+			t.Errorf("ParseWithCharset(%q, %q): got %v, %v, want %s", tt.in, tt.extra, v, err, tt.want)
+		}
+	}
+	for _, s := range []string{"1.2.3", "1.2.3-β.٣+x-y", "0.0.d", "١.٢.٣", "1.2.3-a b", "1.2.3-"} {
+		v, err := ParseWithCharset(s, "")
+		w, werr := Parse(s)
+		if fmt.Sprint(v) != fmt.Sprint(w) || (err == nil) != (werr == nil) {
+			t.Errorf("ParseWithCharset(%q, \"\"): got %v, %v, want %v, %v", s, v, err, w, werr)
+		}
+	}
+	if _, err := Parse("1.2.3-my_build"); err == nil {
+		t.Error("Parse(1.2.3-my_build) succeeded; want error")
+	}
+}
+
+func TestMinBumpToExceed(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"1.2.3", "1.2.4"},
+		{"1.2.3+b5", "1.2.4"},
+		{"1.2.3-rc.1", "1.2.3"},
+		{"1.2.3-rc.1+b5", "1.2.3"},
+		{"0.0.0", "0.0.1"},
+	}
+	for _, tt := range tests {
+		latest := mustParse(tt.in)
+		got := latest.MinBumpToExceed()
+		if got.String() != tt.want {
+			t.Errorf("%s.MinBumpToExceed(): got %v, want %s", tt.in, got, tt.want)
+		}
+		if got.Compare(latest) <= 0 {
+			t.Errorf("%s.MinBumpToExceed(): got %v, which does not exceed it", tt.in, got)
+		}
+		if latest.String() != tt.in {
+			t.Errorf("MinBumpToExceed modified %s to %v", tt.in, latest)
+		}
+	}
+	v, _ := ParseExtended("1.2.3.4")
+	if got := v.MinBumpToExceed(); got.String() != "1.2.4" || got.Compare(v) <= 0 {
+		t.Errorf("1.2.3.4.MinBumpToExceed(): got %v, want 1.2.4", got)
+	}
+}
+
+func TestCoreKeyOf(t *testing.T) {
+	buckets := make(map[CoreKey][]*Version)
+	for _, v := range parseAll("1.2.3", "1.2.3-rc.1", "1.2.4", "1.2.3+b5", "2.0.0") {
+		k := CoreKeyOf(v)
+		buckets[k] = append(buckets[k], v)
+	}
+	want := map[CoreKey]string{
+		{1, 2, 3}: "[1.2.3 1.2.3-rc.1 1.2.3+b5]",
+		{1, 2, 4}: "[1.2.4]",
+		{2, 0, 0}: "[2.0.0]",
+	}
+	if len(buckets) != len(want) {
+		t.Errorf("buckets: got %v, want %v", buckets, want)
+	}
+	for k, w := range want {
+		if got := fmt.Sprint(buckets[k]); got != w {
+			t.Errorf("buckets[%v]: got %s, want %s", k, got, w)
+		}
+	}
+	if got := mustParse("1.2.3-rc.1").CoreKey(); got != [3]int{1, 2, 3} {
+		t.Errorf("1.2.3-rc.1.CoreKey(): got %v, want [1 2 3]", got)
+	}
+}
+
+func TestIdentical(t *testing.T) {
+	tests := []struct {
+		v, w *Version
+		want bool
+	}{
+		{mustParse("1.2.3-rc.1+b5"), mustParse("1.2.3-rc.1+b5"), true},
+		{&Version{Major: 1, Prerelease: []string{}}, &Version{Major: 1}, true},
+		{mustParse("1.2.3-rc.1+b5"), mustParse("1.2.3-rc.1+b6"), false},
+		{mustParse("1.2.3-rc.1"), mustParse("1.2.3-rc.2"), false},
+		{mustParse("1.2.3"), mustParse("1.2.4"), false},
+		{mustParse("1.2.3"), &Version{Epoch: 1, Major: 1, Minor: 2, Patch: 3}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.v.Identical(tt.w); got != tt.want {
+			t.Errorf("%v.Identical(%v): got %v, want %v", tt.v, tt.w, got, tt.want)
+		}
+	}
+}
+
+func BenchmarkIdentical(b *testing.B) {
+	v, w := mustParse("1.2.3-rc.1+b5"), mustParse("1.2.3-rc.1+b5")
+	for i := 0; i < b.N; i++ {
+		v.Identical(w)
+	}
+}
+
+func BenchmarkDeepEqual(b *testing.B) {
+	v, w := mustParse("1.2.3-rc.1+b5"), mustParse("1.2.3-rc.1+b5")
+	for i := 0; i < b.N; i++ {
+		v.DeepEqual(w)
+	}
+}
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"1.2.3", "1.2.3-rc.1+b5", 0},
+		{"1.2.3", "1.2.5", 2},
+		{"1.2.5", "1.2.3", 2},
+		{"1.2.3", "1.4.0", 203},
+		{"1.2.3", "3.0.0", 20203},
+	}
+	for _, tt := range tests {
+		if got := Distance(mustParse(tt.a), mustParse(tt.b)); got != tt.want {
+			t.Errorf("Distance(%s, %s): got %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+	base := mustParse("1.2.3")
+	ordered := parseAll("1.2.4", "1.2.99", "1.3.0", "1.9.0", "2.0.0", "5.0.0")
+	for i := 1; i < len(ordered); i++ {
+		if Distance(base, ordered[i-1]) >= Distance(base, ordered[i]) {
+			t.Errorf("Distance(%v, %v) >= Distance(%v, %v)", base, ordered[i-1], base, ordered[i])
+		}
+	}
+}
+
+func TestFloor(t *testing.T) {
+	tests := []struct {
+		in, minor, major string
+	}{
+		{"1.2.3-rc.1", "1.2.0", "1.0.0"},
+		{"1.2.3+b5", "1.2.0", "1.0.0"},
+		{"0.0.7", "0.0.0", "0.0.0"},
+	}
+	for _, tt := range tests {
+		v := mustParse(tt.in)
+		if got := v.FloorMinor(); got.String() != tt.minor {
+			t.Errorf("%s.FloorMinor(): got %v, want %s", tt.in, got, tt.minor)
+		}
+		if got := v.FloorMajor(); got.String() != tt.major {
+			t.Errorf("%s.FloorMajor(): got %v, want %s", tt.in, got, tt.major)
+		}
+	}
+	v := mustParse("1.2.3-rc.1")
+	for _, f := range []*Version{v.FloorMinor(), v.FloorMajor()} {
+		f.Major = 9
+		if f == v || v.String() != "1.2.3-rc.1" {
+			t.Errorf("floor of %v aliases it", v)
+		}
+	}
+	e, _ := ParseEpoch("2:1.2.3")
+	if got := e.FloorMinor(); got.Epoch != 2 || got.String() != "2:1.2.0" {
+		t.Errorf("2:1.2.3.FloorMinor(): got %v, want 2:1.2.0", got)
+	}
+}
+
+func TestParseInto(t *testing.T) {
+	var v Version
+	for _, s := range []string{"1.2.3-rc.1.x+b5.6", "4.5.6", "7.8.9-a", "1.0.0+b"} {
+		if err := ParseInto(s, &v); err != nil {
+			t.Fatalf("ParseInto(%q): %v", s, err)
+		}
+		if got := v.String(); got != s || !v.Identical(mustParse(s)) {
+			t.Errorf("ParseInto(%q) stored %s", s, got)
+		}
+	}
+	if v.Prerelease != nil {
+		t.Errorf("ParseInto(1.0.0+b) left prerelease %q", v.Prerelease)
+	}
+	before := v.String()
+	for _, s := range []string{"1.2", "", "x"} {
+		if err := ParseInto(s, &v); !errors.Is(err, ErrInvalid) {
+			t.Errorf("ParseInto(%q): got %v, want ErrInvalid", s, err)
+		}
+		if v.String() != before {
+			t.Errorf("failed ParseInto(%q) changed v to %v", s, &v)
+		}
+	}
+}
+
+func BenchmarkParse(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := Parse("1.2.3-rc.1+b5"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseInto(b *testing.B) {
+	b.ReportAllocs()
+	var v Version
+	for i := 0; i < b.N; i++ {
+		if err := ParseInto("1.2.3-rc.1+b5", &v); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestSelectMVS(t *testing.T) {
+	tests := []struct {
+		in   []*Version
+		want string
+	}{
+		{parseAll("1.2.0", "1.4.1", "1.3.9"), "1.4.1"},
+		{parseAll("1.4.1-rc.1", "1.4.0"), "1.4.1-rc.1"},
+		{parseAll("1.4.1-rc.1", "1.4.1"), "1.4.1"},
+		{parseAll("2.0.0"), "2.0.0"},
+		{nil, "<nil>"},
+	}
+	for _, tt := range tests {
+		if got := fmt.Sprint(SelectMVS(tt.in)); got != tt.want {
+			t.Errorf("SelectMVS(%v): got %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCompareWithBuild(t *testing.T) {
+	tests := []struct {
+		v, w string
+		want int
+	}{
+		{"1.0.0+2", "1.0.0+10", -1},
+		{"1.0.0+10", "1.0.0+2", 1},
+		{"1.0.0+2", "1.0.0+02", 0},
+		{"1.0.0+b.2", "1.0.0+b.10", -1},
+		{"1.0.0+b10", "1.0.0+b2", -1},
+		{"1.0.0+10", "1.0.0+a", -1},
+		{"1.0.0+1", "1.0.0+1.0", -1},
+		{"1.0.0+1", "1.0.0+1", 0},
+		{"1.0.0", "1.0.0+1", -1},
+		{"1.0.0-rc.1+99", "1.0.0+1", -1},
+	}
+	for _, tt := range tests {
+		v, w := mustParse(tt.v), mustParse(tt.w)
+		if got := v.CompareWithBuild(w); got != tt.want {
+			t.Errorf("%s.CompareWithBuild(%s): got %d, want %d", tt.v, tt.w, got, tt.want)
+		}
+	}
+	if mustParse("1.0.0+2").Compare(mustParse("1.0.0+10")) != 0 {
+		t.Error("Compare(1.0.0+2, 1.0.0+10) != 0")
+	}
+}
+
+func TestMarshalLines(t *testing.T) {
+	vs := parseAll("1.2.3", "1.2.3-rc.1+b5", "0.0.1-β")
+	b := MarshalLines(vs)
+	if got, want := string(b), "1.2.3\n1.2.3-rc.1+b5\n0.0.1-β\n"; got != want {
+		t.Errorf("MarshalLines(%v): got %q, want %q", vs, got, want)
+	}
+	got, err := ParseLines(b)
+	if err != nil || fmt.Sprint(got) != fmt.Sprint(vs) {
+		t.Errorf("ParseLines(%q): got %v, %v, want %v", b, got, err, vs)
+	}
+	if b := MarshalLines(nil); len(b) != 0 {
+		t.Errorf("MarshalLines(nil): got %q, want empty", b)
+	}
+}
+
+func TestParseLines(t *testing.T) {
+	vs, err := ParseLines([]byte("\n 1.2.3 \n\n2.0.0\r\n"))
+	if err != nil || fmt.Sprint(vs) != "[1.2.3 2.0.0]" {
+		t.Errorf("ParseLines: got %v, %v, want [1.2.3 2.0.0]", vs, err)
+	}
+	_, err = ParseLines([]byte("1.2.3\n\n1.2\n2.0.0\n"))
+	if !errors.Is(err, ErrInvalid) || err.Error() != `line 3: truncated version: missing patch "1.2"` {
+		t.Errorf("ParseLines with bad line 3: got %v, want line 3 error", err)
+	}
+}
+
+func TestIsDowngradeTo(t *testing.T) {
+	tests := []struct {
+		current, target string
+		want            bool
+	}{
+		{"1.2.3", "1.2.2", true},
+		{"1.2.3", "1.2.4", false},
+		{"1.2.3", "1.2.3", false},
+		{"1.2.3", "1.2.3+b5", false},
+		{"1.2.3", "1.2.3-rc.1", true},
+		{"1.2.3-rc.1", "1.2.3", false},
+		{"1.2.3-rc.2", "1.2.3-rc.1", true},
+		{"1.2.3-rc.1", "1.2.3-beta", true},
+		{"2.0.0-alpha", "1.9.9", true},
+		{"1.9.9", "2.0.0-alpha", false},
+	}
+	for _, tt := range tests {
+		if got := mustParse(tt.current).IsDowngradeTo(mustParse(tt.target)); got != tt.want {
+			t.Errorf("%s.IsDowngradeTo(%s): got %v, want %v", tt.current, tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestPrereleaseOrder(t *testing.T) {
+	opts := CompareOpts{PrereleaseOrder: map[string]int{"dev": 0, "alpha": 1, "beta": 2, "rc": 3}}
+	tests := []struct {
+		v, w string
+		want int
+	}{
+		{"1.0.0-dev", "1.0.0-alpha", -1},
+		{"1.0.0-alpha", "1.0.0-dev", 1},
+		{"1.0.0-rc.1", "1.0.0-beta.5", 1},
+		{"1.0.0-dev.2", "1.0.0-dev.10", -1},
+		{"1.0.0-dev", "1.0.0-dev.1", -1},
+		{"1.0.0-rc", "1.0.0", -1},
+		{"1.0.0-nightly", "1.0.0-alpha", 1},
+		{"1.0.0-alpha", "1.0.0-alpha", 0},
+	}
+	for _, tt := range tests {
+		v, w := mustParse(tt.v), mustParse(tt.w)
+		if got := v.CompareWith(w, opts); got != tt.want {
+			t.Errorf("%s.CompareWith(%s, %v): got %d, want %d", tt.v, tt.w, opts, got, tt.want)
+		}
+	}
+	if got := mustParse("1.0.0-dev").Compare(mustParse("1.0.0-alpha")); got != 1 {
+		t.Errorf("1.0.0-dev.Compare(1.0.0-alpha): got %d, want 1", got)
+	}
+}
+
+func TestRepair(t *testing.T) {
+	tests := []struct {
+		in, want string
+		fixes    []string
+	}{
+		{"1.2.3", "1.2.3", nil},
+		{"v01.2", "1.2.0", []string{
+			`removed "v" prefix`,
+			"added missing patch number",
+			`removed leading zeros from major number "01"`,
+		}},
+		{" 1.2.3 ", "1.2.3", []string{"trimmed white space"}},
+		{"V1-rc..01+b..c", "1.0.0-rc.1+b.c", []string{
+			`removed "V" prefix`,
+			"added missing minor number",
+			"added missing patch number",
+			"removed empty prerelease identifier",
+			`removed leading zeros from prerelease identifier "01"`,
+			"removed empty build identifier",
+		}},
+	}
+	for _, tt := range tests {
+		v, fixes, err := Repair(tt.in)
+		if err != nil || v.String() != tt.want || fmt.Sprintf("%q", fixes) != fmt.Sprintf("%q", tt.fixes) {
+			t.Errorf("Repair(%q): got %v, %q, %v, want %s, %q", tt.in, v, fixes, err, tt.want, tt.fixes)
+		}
+	}
+	for _, s := range []string{"", "x.y.z", "1.2.3.4", "1..2", "1.2.3-a b", "1234567890.0.0", "1.2.3-β", "1.2.3+٣", "v1.2-rc..Ωmega"} {
+		if v, fixes, err := Repair(s); !errors.Is(err, ErrInvalid) {
+			t.Errorf("Repair(%q): got %v, %q, %v, want ErrInvalid", s, v, fixes, err)
+		}
+	}
+}
+
+func TestAddedRemoved(t *testing.T) {
+	tests := []struct {
+		old, new       []*Version
+		added, removed string
+	}{
+		{parseAll("1.0.0", "1.1.0"), parseAll("1.1.0", "1.2.0", "1.0.0"), "[1.2.0]", "[]"},
+		{parseAll("1.0.0", "1.1.0"), parseAll("1.1.0"), "[]", "[1.0.0]"},
+		{parseAll("1.0.0+a"), parseAll("1.0.0+b", "1.0.0-rc.1"), "[1.0.0-rc.1]", "[]"},
+		{parseAll("2.0.0", "1.0.0"), parseAll("3.0.0", "2.0.0+x", "3.0.0+y", "0.1.0"), "[0.1.0 3.0.0]", "[1.0.0]"},
+		{nil, parseAll("1.0.0"), "[1.0.0]", "[]"},
+	}
+	for _, tt := range tests {
+		if got := fmt.Sprint(Added(tt.old, tt.new)); got != tt.added {
+			t.Errorf("Added(%v, %v): got %s, want %s", tt.old, tt.new, got, tt.added)
+		}
+		if got := fmt.Sprint(Removed(tt.old, tt.new)); got != tt.removed {
+			t.Errorf("Removed(%v, %v): got %s, want %s", tt.old, tt.new, got, tt.removed)
+		}
+	}
+}
+
+func TestParseCandidates(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"1.2.3", "[1.2.3]"},
+		{"1.2.3.4", "[1.2.3 1.2.3.4]"},
+		{"1:2.3.4", "[1.0.0 1:2.3.4]"},
+		{" 1.2.3 ", "[1.2.3]"},
+		{"v1.2", "[1.2.0]"},
+		{"release-7", "[7.0.0]"},
+		{"x", "[]"},
+		{"", "[]"},
+	}
+	for _, tt := range tests {
+		if got := fmt.Sprint(ParseCandidates(tt.in)); got != tt.want {
+			t.Errorf("ParseCandidates(%q): got %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSlug(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"1.2.3", "1.2.3"},
+		{"1.2.3-rc.1+build", "1.2.3-rc.1_build"},
+		{"3.24.3-β+20150115102400", "3.24.3-~0003b2_20150115102400"},
+		{"1.0.0-ａ", "1.0.0-~00ff41"},
+	}
+	for _, tt := range tests {
+		if got := mustParse(tt.in).Slug(); got != tt.want {
+			t.Errorf("%s.Slug(): got %q, want %q", tt.in, got, tt.want)
+		}
+	}
+	seen := make(map[string]string)
+	for _, s := range []string{"1.0.0+a", "1.0.0-a", "1.0.0-β", "1.0.0-γ", "1.0.0-a.b", "1.0.0-a+b", "1.0.0+a.b"} {
+		slug := mustParse(s).Slug()
+		if prev, ok := seen[slug]; ok {
+			t.Errorf("%s and %s have the same slug %q", prev, s, slug)
+		}
+		seen[slug] = s
+	}
+}
+
+func TestCompareIgnoringPrerelease(t *testing.T) {
+	tests := []struct {
+		v, w string
+		want int
+	}{
+		{"1.2.3-rc.1", "1.2.3", 0},
+		{"1.2.3", "1.2.3-rc.1", 0},
+		{"1.2.3-alpha", "1.2.3-rc.2+b5", 0},
+		{"1.2.3", "1.2.4-rc.1", -1},
+		{"1.2.4-rc.1", "1.2.3", 1},
+		{"1.2.3-rc.1", "1.3.0", -1},
+		{"2.0.0-rc.1", "1.9.9", 1},
+	}
+	for _, tt := range tests {
+		if got := mustParse(tt.v).CompareIgnoringPrerelease(mustParse(tt.w)); got != tt.want {
+			t.Errorf("%s.CompareIgnoringPrerelease(%s): got %d, want %d", tt.v, tt.w, got, tt.want)
+		}
+	}
+}
+
+func TestParseRequireLines(t *testing.T) {
+	b := []byte(`require (
+	example.com/a v1.2.3
+	example.com/b v2.0.1+incompatible // indirect
+
+	example.com/c 0.4.0-rc.1
+)
+require example.com/d v1.0.0
+`)
+	m, err := ParseRequireLines(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"example.com/a": "1.2.3",
+		"example.com/b": "2.0.1+incompatible",
+		"example.com/c": "0.4.0-rc.1",
+		"example.com/d": "1.0.0",
+	}
+	if len(m) != len(want) {
+		t.Errorf("ParseRequireLines: got %v, want %v", m, want)
+	}
+	for k, w := range want {
+		if got := fmt.Sprint(m[k]); got != w {
+			t.Errorf("ParseRequireLines[%q]: got %s, want %s", k, got, w)
+		}
+	}
+	tests := []struct {
+		in, prefix string
+	}{
+		{"require (\n\texample.com/a v1.2\n)\n", "line 2: "},
+		{"example.com/a v1.2.3\nexample.com/b\n", "line 2: "},
+		{"example.com/a v1.0.0+incompatible\n", "line 1: "},
+	}
+	for _, tt := range tests {
+		if _, err := ParseRequireLines([]byte(tt.in)); err == nil || !strings.HasPrefix(err.Error(), tt.prefix) {
+			t.Errorf("ParseRequireLines(%q): got %v, want error with prefix %q", tt.in, err, tt.prefix)
+		}
+	}
+}
+
+func TestSamePrerelease(t *testing.T) {
+	tests := []struct {
+		v, w string
+		want bool
+	}{
+		{"1.2.3-rc.1", "2.0.0-rc.1", true},
+		{"1.2.3-rc.1", "1.2.3-rc.2", false},
+		{"1.2.3", "4.5.6", true},
+		{"1.2.3", "1.2.3-rc.1", false},
+		{"1.2.3-rc.1+a", "1.2.3-rc.1+b", true},
+		{"1.2.3-rc", "1.2.3-rc.1", false},
+	}
+	for _, tt := range tests {
+		if got := mustParse(tt.v).SamePrerelease(mustParse(tt.w)); got != tt.want {
+			t.Errorf("%s.SamePrerelease(%s): got %v, want %v", tt.v, tt.w, got, tt.want)
+		}
+	}
+	if !(&Version{Prerelease: []string{}}).SamePrerelease(&Version{}) {
+		t.Error("empty and nil prereleases differ")
+	}
+}
+
+func TestSortStable(t *testing.T) {
+	orders := [][]string{
+		{"1.0.0+b", "1.0.0+a", "0.9.0", "1.0.0", "1.0.0-rc.1+z", "1.0.0-rc.1+y"},
+		{"1.0.0-rc.1+y", "1.0.0", "1.0.0+a", "1.0.0-rc.1+z", "0.9.0", "1.0.0+b"},
+		{"0.9.0", "1.0.0-rc.1+z", "1.0.0+b", "1.0.0-rc.1+y", "1.0.0+a", "1.0.0"},
+	}
+	const want = "[0.9.0 1.0.0-rc.1+y 1.0.0-rc.1+z 1.0.0 1.0.0+a 1.0.0+b]"
+	for _, order := range orders {
+		vs := parseAll(order...)
+		SortStable(vs)
+		if got := fmt.Sprint(vs); got != want {
+			t.Errorf("SortStable(%q): got %s, want %s", order, got, want)
+		}
+	}
+}
+
+func TestCommonPrefix(t *testing.T) {
+	tests := []struct {
+		in   []*Version
+		want string
+	}{
+		{parseAll("1.2.3", "1.2.7", "1.2.9"), "1.2"},
+		{parseAll("1.2.3", "1.4.3", "1.2.3"), "1"},
+		{parseAll("1.2.3", "1.2.3-rc.1", "1.2.3+b5"), "1.2.3"},
+		{parseAll("1.2.3", "1.2.4", "1.3.3"), "1"},
+		{parseAll("1.2.3", "2.2.3"), ""},
+		{parseAll("1.2.3"), "1.2.3"},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		if got := CommonPrefix(tt.in); got != tt.want {
+			t.Errorf("CommonPrefix(%v): got %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCompareTo(t *testing.T) {
+	vs := parseAll("1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-beta.2", "1.0.0-beta.11", "1.0.0", "1.0.0+b", "1.1.0")
+	for _, v := range vs {
+		for _, w := range vs {
+			if got, want := v.CompareTo(w), v.Compare(w); got != want {
+				t.Errorf("%v.CompareTo(%v): got %d, want %d", v, w, got, want)
+			}
+		}
+	}
+}
+
+func TestPatchRange(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     string
+	}{
+		{"1.2.0", "1.2.5", "[1.2.0 1.2.1 1.2.2 1.2.3 1.2.4 1.2.5]"},
+		{"1.2.3-rc.1", "1.2.4+b5", "[1.2.3 1.2.4]"},
+		{"1.2.3", "1.2.3", "[1.2.3]"},
+	}
+	for _, tt := range tests {
+		vs, err := PatchRange(mustParse(tt.from), mustParse(tt.to))
+		if err != nil || fmt.Sprint(vs) != tt.want {
+			t.Errorf("PatchRange(%s, %s): got %v, %v, want %s", tt.from, tt.to, vs, err, tt.want)
+		}
+	}
+	errs := []struct {
+		from, to *Version
+	}{
+		{mustParse("1.2.0"), mustParse("1.3.5")},
+		{mustParse("1.2.0"), mustParse("2.2.5")},
+		{mustParse("1.2.5"), mustParse("1.2.0")},
+		{mustParse("1.2.0"), &Version{Epoch: 1, Major: 1, Minor: 2, Patch: 5}},
+		{mustParse("1.2.0"), mustParse("1.2.10000")},
+	}
+	for _, tt := range errs {
+		if vs, err := PatchRange(tt.from, tt.to); err == nil {
+			t.Errorf("PatchRange(%v, %v): got %v, want error", tt.from, tt.to, vs)
+		}
+	}
+	if vs, err := PatchRange(mustParse("1.2.0"), mustParse("1.2.9999")); err != nil || len(vs) != 10000 {
+		t.Errorf("PatchRange(1.2.0, 1.2.9999) has %d versions, %v; want 10000", len(vs), err)
+	}
+}
+
+func TestParseNormalized(t *testing.T) {
+	tests := []struct {
+		in, want   string
+		normalized bool
+	}{
+		{"1.2.3", "1.2.3", false},
+		{"1.2.3-rc.1+b5", "1.2.3-rc.1+b5", false},
+		{"1.02.3", "1.2.3", true},
+		{"01.2.3", "1.2.3", true},
+		{"1.2.003-rc.1", "1.2.3-rc.1", true},
+		{"0.0.0", "0.0.0", false},
+		{"invalid", "<nil>", false},
+	}
+	for _, tt := range tests {
+		v, normalized := ParseNormalized(tt.in)
+		if fmt.Sprint(v) != tt.want || normalized != tt.normalized {
+			t.Errorf("ParseNormalized(%q): got %v, %v, want %s, %v", tt.in, v, normalized, tt.want, tt.normalized)
+		}
+	}
+}
+
+func TestDecPrerelease(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"1.2.3-rc.3", "1.2.3-rc.2"},
+		{"1.2.3-rc.10+b5", "1.2.3-rc.9"},
+		{"1.2.3-2", "1.2.3-1"},
+	}
+	for _, tt := range tests {
+		v := mustParse(tt.in)
+		got, err := v.DecPrerelease()
+		if err != nil || got.String() != tt.want {
+			t.Errorf("%s.DecPrerelease(): got %v, %v, want %s", tt.in, got, err, tt.want)
+		}
+		if v.String() != tt.in {
+			t.Errorf("DecPrerelease modified %s to %v", tt.in, v)
+		}
+	}
+	for _, s := range []string{"1.2.3-rc.1", "1.2.3-rc.0", "1.2.3-rc", "1.2.3", "1.2.3-1.rc"} {
+		if got, err := mustParse(s).DecPrerelease(); err == nil {
+			t.Errorf("%s.DecPrerelease(): got %v, want error", s, got)
+		}
+	}
+}
+
+func TestCompatibleUnder(t *testing.T) {
+	tests := []struct {
+		v, w                       string
+		caret, major, minor, exact bool
+	}{
+		{"1.2.3", "1.2.3", true, true, true, true},
+		{"1.2.3", "1.2.3+b5", true, true, true, true},
+		{"1.2.3", "1.2.4", true, true, true, false},
+		{"1.2.3", "1.4.0", true, true, false, false},
+		{"1.2.3", "1.2.2", false, true, true, false},
+		{"1.2.3", "1.2.3-rc.1", false, true, true, false},
+		{"1.2.3", "1.5.0-rc.1", true, true, false, false},
+		{"1.2.3", "2.0.0-rc.1", false, false, false, false},
+		{"1.2.3", "2.0.0", false, false, false, false},
+		{"0.2.3", "0.2.9", true, true, true, false},
+		{"0.2.3", "0.3.0", false, true, false, false},
+	}
+	for _, tt := range tests {
+		v, w := mustParse(tt.v), mustParse(tt.w)
+		for _, p := range []struct {
+			name   string
+			policy Policy
+			want   bool
+		}{
+			{"SemverCaret", SemverCaret, tt.caret},
+			{"SameMajor", SameMajor, tt.major},
+			{"SameMinor", SameMinor, tt.minor},
+			{"Exact", Exact, tt.exact},
+		} {
+			if got := v.CompatibleUnder(w, p.policy); got != p.want {
+				t.Errorf("%s.CompatibleUnder(%s, %s): got %v, want %v", tt.v, tt.w, p.name, got, p.want)
+			}
+		}
+	}
+	v, w := mustParse("1.2.3"), &Version{Epoch: 1, Major: 1, Minor: 2, Patch: 3}
+	for _, p := range []Policy{SemverCaret, SameMajor, SameMinor, Exact, Policy(99)} {
+		if v.CompatibleUnder(w, p) {
+			t.Errorf("%v.CompatibleUnder(%v, %d): got true, want false", v, w, p)
+		}
+	}
+}
+
+func TestParseTruncated(t *testing.T) {
+	tests := []struct {
+		in, msg   string
+		truncated bool
+	}{
+		{"1.2.", `truncated version: missing patch "1.2."`, true},
+		{"1.2", `truncated version: missing patch "1.2"`, true},
+		{"1.", `truncated version: missing minor "1."`, true},
+		{"1", `truncated version: missing minor "1"`, true},
+		{"1.2.x", `invalid version "1.2.x"`, false},
+		{"1..2", `invalid version "1..2"`, false},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.in)
+		if err == nil || err.Error() != tt.msg {
+			t.Errorf("Parse(%q): got %v, want %q", tt.in, err, tt.msg)
+		}
+		if errors.Is(err, ErrTruncated) != tt.truncated {
+			t.Errorf("errors.Is(%v, ErrTruncated) != %v", err, tt.truncated)
+		}
+		if !errors.Is(err, ErrInvalid) {
+			t.Errorf("Parse(%q) error %v is not ErrInvalid", tt.in, err)
+		}
+	}
+}
+
+func TestFinalVersion(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"1.2.0-rc.1", "1.2.0"},
+		{"1.2.0-rc.1+b5", "1.2.0"},
+		{"1.2.0", "1.2.0"},
+		{"1.2.0+b5", "1.2.0+b5"},
+	}
+	for _, tt := range tests {
+		v := mustParse(tt.in)
+		got := v.FinalVersion()
+		if got.String() != tt.want {
+			t.Errorf("%s.FinalVersion(): got %v, want %s", tt.in, got, tt.want)
+		}
+		if got == v {
+			t.Errorf("%s.FinalVersion() returned v", tt.in)
+		}
+		got.Major = 9
+		if len(got.Build) != 0 {
+			got.Build[0] = "changed"
+		}
+		if v.String() != tt.in {
+			t.Errorf("FinalVersion of %s aliases it: %v", tt.in, v)
+		}
+	}
+}
+
+// repeatedPairs returns n comparisons drawn from a few distinct pairs, as a
+// resolver makes.
+func repeatedPairs(n int) [][2]*Version {
+	vs := parseAll("1.0.0-alpha.beta.1", "1.0.0-alpha.beta.2", "1.0.0-rc.11", "1.0.0-rc.2", "1.0.0", "1.2.3+b5")
+	pairs := make([][2]*Version, n)
+	for i := range pairs {
+		pairs[i] = [2]*Version{vs[i%len(vs)], vs[(i/len(vs))%len(vs)]}
+	}
+	return pairs
+}
+
+func BenchmarkCompareRepeated(b *testing.B) {
+	pairs := repeatedPairs(1000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p := pairs[i%len(pairs)]
+		p[0].Compare(p[1])
+	}
+}
+
+func BenchmarkDefaultComparerRepeated(b *testing.B) {
+	pairs := repeatedPairs(1000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p := pairs[i%len(pairs)]
+		DefaultComparer.Compare(p[0], p[1])
+	}
+}
+
+func TestPrecedenceTuple(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"1.2.3", "0 1 2 3 [] []"},
+		{"1.2.3+b5", "0 1 2 3 [] []"},
+		{"1.2.3-rc.1+b5", "0 1 2 3 [] [rc 1]"},
+		{"1.2.3.4-a", "0 1 2 3 [4] [a]"},
+		{"1:1.0.0", "1 1 0 0 [] []"},
+		{"2:1.0.0", "2 1 0 0 [] []"},
+	}
+	for _, tt := range tests {
+		v := parseMixedMust(t, tt.in)
+		epoch, major, minor, patch, extra, pre := v.PrecedenceTuple()
+		if got := fmt.Sprint(epoch, major, minor, patch, extra, pre); got != tt.want {
+			t.Errorf("%s.PrecedenceTuple(): got %s, want %s", tt.in, got, tt.want)
+		}
+	}
+	v := parseMixedMust(t, "1.2.3.4-rc.1")
+	_, _, _, _, extra, pre := v.PrecedenceTuple()
+	extra[0], pre[0] = 9, "changed"
+	if v.String() != "1.2.3.4-rc.1" {
+		t.Errorf("PrecedenceTuple aliases %v", v)
+	}
+}
+
+func TestIsLowestPrerelease(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{"1.2.0-0", true},
+		{"1.2.0-0+b5", true},
+		{"1.2.0-0.0", false},
+		{"1.2.0-1", false},
+		{"1.2.0-alpha", false},
+		{"1.2.0", false},
+	}
+	for _, tt := range tests {
+		if got := mustParse(tt.in).IsLowestPrerelease(); got != tt.want {
+			t.Errorf("%s.IsLowestPrerelease(): got %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestLowestPrerelease(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"1.2.0", "1.2.0-0"},
+		{"1.2.0+b5", "1.2.0-0"},
+		{"1.2.0-rc.1", "1.2.0-0"},
+		{"0.0.0", "0.0.0-0"},
+	}
+	for _, tt := range tests {
+		v := mustParse(tt.in)
+		low := v.LowestPrerelease()
+		if low.String() != tt.want || !low.IsLowestPrerelease() {
+			t.Errorf("%s.LowestPrerelease(): got %v, want %s", tt.in, low, tt.want)
+		}
+		if v.String() != tt.in {
+			t.Errorf("LowestPrerelease modified %s to %v", tt.in, v)
+		}
+		for _, w := range parseAll(tt.want+".0", "1.2.0-00a", "1.2.0-alpha", "1.2.0") {
+			if sameCore(w, low) && w.Compare(low) <= 0 {
+				t.Errorf("%v does not have higher precedence than %v", w, low)
+			}
+		}
+	}
+}